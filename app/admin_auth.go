@@ -0,0 +1,140 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	errors "github.com/sanches1984/gopkg-errors"
+)
+
+// Route groups of admin server for WithAdminAuth
+const (
+	// AdminGroupDocs is table of contents, swagger and grpc documentation
+	AdminGroupDocs = "docs"
+	// AdminGroupMetrics is prometheus metrics
+	AdminGroupMetrics = "metrics"
+	// AdminGroupHealth is liveness and readiness probes
+	AdminGroupHealth = "health"
+	// AdminGroupDebug is pprof and runtime log level
+	AdminGroupDebug = "debug"
+)
+
+var adminGroups = []string{AdminGroupDocs, AdminGroupMetrics, AdminGroupHealth, AdminGroupDebug}
+
+// AdminAuth restricts access to admin routes. When AllowedIPs is set client address must match it,
+// when BasicUsers or BearerTokens are set request must have valid credentials of any of them
+type AdminAuth struct {
+	// BasicUsers maps user name to password
+	BasicUsers   map[string]string
+	BearerTokens []string
+	// AllowedIPs contains IPs and CIDRs, e.g. "10.0.0.0/8"
+	AllowedIPs []string
+}
+
+func isAdminGroup(group string) bool {
+	for _, g := range adminGroups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+type adminAuth struct {
+	AdminAuth
+	networks []*net.IPNet
+}
+
+func newAdminAuth(auth AdminAuth) (*adminAuth, error) {
+	ret := &adminAuth{AdminAuth: auth}
+	for _, s := range auth.AllowedIPs {
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, errors.Internal.ErrWrap(context.Background(), "Invalid admin allowed IP", err).WithPayloadKV("ip", s)
+		}
+		ret.networks = append(ret.networks, network)
+	}
+	return ret, nil
+}
+
+func (auth *adminAuth) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !auth.allowedIP(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		if !auth.authorized(r) {
+			if len(auth.BasicUsers) > 0 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+			}
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedIP checks address of connection, forwarded headers are not trusted
+func (auth *adminAuth) allowedIP(r *http.Request) bool {
+	if len(auth.networks) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range auth.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (auth *adminAuth) authorized(r *http.Request) bool {
+	if len(auth.BasicUsers) == 0 && len(auth.BearerTokens) == 0 {
+		return true
+	}
+	if user, password, ok := r.BasicAuth(); ok {
+		if expected, found := auth.BasicUsers[user]; found && secureEqual(password, expected) {
+			return true
+		}
+	}
+	header := r.Header.Get("Authorization")
+	if strings.HasPrefix(header, "Bearer ") {
+		token := strings.TrimPrefix(header, "Bearer ")
+		for _, expected := range auth.BearerTokens {
+			if secureEqual(token, expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// adminRouter returns admin mux with auth of group
+func (a *App) adminRouter(group string) chi.Router {
+	if auth, ok := a.adminAuth[group]; ok {
+		return a.httpAdminServer.With(auth.middleware)
+	}
+	return a.httpAdminServer
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminAuth(t *testing.T) {
+	a := &App{httpAdminServer: chi.NewMux(), adminAuth: make(map[string]*adminAuth)}
+	assert.NotNil(t, WithAdminAuth(AdminAuth{}, "unknown")(a))
+	assert.NotNil(t, WithAdminAuth(AdminAuth{AllowedIPs: []string{"10.0.0.0/33"}})(a))
+	assert.Nil(t, WithAdminAuth(AdminAuth{
+		BasicUsers:   map[string]string{"admin": "secret"},
+		BearerTokens: []string{"token"},
+		AllowedIPs:   []string{"10.0.0.0/8", "127.0.0.1"},
+	}, AdminGroupMetrics)(a))
+
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	a.adminRouter(AdminGroupMetrics).Get("/metrics", ok)
+	a.adminRouter(AdminGroupHealth).Get("/health/live", ok)
+
+	request := func(remoteAddr, path string, prepare func(r *http.Request)) int {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = remoteAddr
+		if prepare != nil {
+			prepare(r)
+		}
+		w := httptest.NewRecorder()
+		a.httpAdminServer.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	t.Run("Public", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("192.168.0.1:1234", "/health/live", nil))
+	})
+
+	t.Run("IP", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request("192.168.0.1:1234", "/metrics", func(r *http.Request) {
+			r.SetBasicAuth("admin", "secret")
+		}))
+	})
+
+	t.Run("Credentials", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, request("10.1.2.3:1234", "/metrics", nil))
+		assert.Equal(t, http.StatusUnauthorized, request("10.1.2.3:1234", "/metrics", func(r *http.Request) {
+			r.SetBasicAuth("admin", "wrong")
+		}))
+		assert.Equal(t, http.StatusOK, request("10.1.2.3:1234", "/metrics", func(r *http.Request) {
+			r.SetBasicAuth("admin", "secret")
+		}))
+		assert.Equal(t, http.StatusOK, request("127.0.0.1:1234", "/metrics", func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer token")
+		}))
+	})
+}
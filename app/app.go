@@ -8,17 +8,26 @@ import (
 	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	"github.com/opentracing/opentracing-go"
 	"github.com/sanches1984/gopkg-app/middleware"
+	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 	"net"
 	"net/http"
 	"os"
 	"regexp"
+	"sync"
 	"syscall"
 	"time"
 
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/sanches1984/gopkg-app/certs"
 	"github.com/sanches1984/gopkg-app/client/sentry"
 	"github.com/sanches1984/gopkg-app/closer"
+	"github.com/sanches1984/gopkg-app/grpcweb"
+	"github.com/sanches1984/gopkg-app/health"
+	"github.com/sanches1984/gopkg-app/loglevel"
 	"github.com/sanches1984/gopkg-app/metrics"
 	swaggerui "github.com/sanches1984/gopkg-app/swagger"
 	pkgtransport "github.com/sanches1984/gopkg-app/transport"
@@ -39,13 +48,9 @@ import (
 	"google.golang.org/grpc"
 )
 
-var (
-	gracefulDelay   = time.Duration(3) * time.Second
-	gracefulTimeout = time.Duration(10) * time.Second
-)
-
 type App struct {
-	config Config
+	config  Config
+	metrics *metrics.Registry
 
 	httpServer        *chi.Mux
 	httpListener      net.Listener
@@ -53,34 +58,76 @@ type App struct {
 	httpAdminListener net.Listener
 	grpcServer        *grpc.Server
 	grpcListener      net.Listener
+	grpcOnHTTPPort    bool
+	grpcWeb           []grpcweb.Option
 
-	unaryInterceptor []grpc.UnaryServerInterceptor
-	publicMiddleware []func(http.Handler) http.Handler
+	unaryInterceptor  []grpc.UnaryServerInterceptor
+	streamInterceptor []grpc.StreamServerInterceptor
+	unaryChain        grpc.UnaryServerInterceptor
+	publicMiddleware  []func(http.Handler) http.Handler
 
 	tracer *opentracing.Tracer
 
-	publicCloser *closer.Closer
+	publicCloser    *closer.Closer
+	defaultGraceful Graceful
+	serverGraceful  map[string]Graceful
+	health          *health.Registry
+	grpcHealth      *grpchealth.Server
+
+	grpcServerConfig       GRPCServerConfig
+	grpcServerOption       []grpc.ServerOption
+	customHTTPServerConfig map[string]HTTPServerConfig
+	tlsReloader            map[string]*certs.Reloader
+	certReloader           map[string]*certs.Reloader
+
+	workers   []*worker
+	failureMu sync.Mutex
+	failure   error
+
+	adminAuth  map[string]*adminAuth
+	startTime  time.Time
+	infoConfig interface{}
+	inventory  Inventory
+
+	configWatcher *ConfigWatcher
 
 	favicon             []byte
 	adminURLPrefix      string
 	customPublicHandler []PublicHandler
-	customPublicCloser  PublicCloserFnMap
+	customPublicCloser  map[string]publicCloser
 	customSwaggerOption []swagger.Option
 	customEnablePprof   bool
 }
 
 func NewApp(ctx context.Context, config Config, option ...OptionFn) (*App, error) {
 	pkgtransport.Override(nil)
-	metrics.AddBasicCollector(config.Name)
+	appMetrics := metrics.NewRegistry(config.Name)
+	if err := appMetrics.AddBuildInfo(buildInfoLabels(config)); err != nil {
+		return nil, err
+	}
+	// package level metrics API of previous versions uses registry of the last App
+	metrics.SetDefault(appMetrics)
 
 	favicon, _ := base64.StdEncoding.DecodeString("AAABAAEAEBAAAAEAIABoBAAAFgAAACgAAAAQAAAAIAAAAAEAIAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA//////////////////////////////////7+//3+/v/9/v7//v79//7++v/+/v3//f7+//3+/v8AAAAA//////////////////////////////////////7+/v/8/v3/+vfu//PPlv/vumz/8cF4//bmxv/9/v3//f79///////////////////////////////////////+/v7//P77//TGg//2s1X/+rRS//q0Uv/ws1v/+OvR//7+/f///////////////////////////////////////v7+//n05P/0slj/+rNT//mzU//6s1L/+rNT//PPmP/+/v7///////////////////////////////////////7+/v/48+P/87NX//i0Uv/5tFP/+LRS//mzVP/zz5X//v79///////////////////////////////////////+/v7/+/37//LDgf/4s1P/+LRT//m0Uv/3s1n/9erQ//3+/v///////////////////////////////////////P7+//3+/f/59+r/8syS//S4Z//zv3P/9ePF//z+/P/+/v3///////////////////////////////////////7+/v/+/v7//f79//z+/P/+/fn//f75//3+/f/+/v3//v7+//3+/v/8/f3/9vv7/7zq9/+d4Pf/uen3//T7/P/7/f3//f7+//v8/f/wz9j/6Ka0/+q0wP/68PP/+/7+//7+/v/7/v7/+f38/4rX9P9VyPX/Usj3/1HJ9f+F1fX/9fz9//3+/v/or7r/42B3/+dfdv/oX3f/3nSH//ns8P/9/v7//f3+/8zv+f9UyPX/Tsn3/1HI9/9QyPj/Usj1/8ft+P/58PP/32V6/+lfd//nX3f/5193/+dfd//nqrb//v7+//v9/f+66vj/Usj3/1HI9/9RyPf/Ucj3/1LI9/+x5vn/8uLm/+Bgd//nX3f/5193/+dfd//oX3f/5Zem//7+/v/9/v7/3vT6/1jK8/9Ryff/Ucn3/1LI9/9TyvT/2PL7//z4+v/gb4T/5l92/+dfd//nX3f/4193/+66xf/+/v7//v7+//z9/v+z5vj/XMvz/1XI9v9Zy/L/quL3//r9/v/9/v7/79LY/+Btgf/lX3b/4mF3/+OWpP/7+fv//v7+///////+/v7//P7+/+b4+//N8Pn/5fb7//v9/P/9/v7/+v7+//z8/f/68/b/79Xb//Ti5//8/f3//f7+//3+/v8AAAAA//////7+/v/7/v7//P7+//3+/v/9/v7/+v7+//z+/v/7/v7//P7+//3+///9/v7//P7+//z+/v8AAAAAgAEAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAgAEAAA==")
 	a := &App{
 		config:             config,
 		favicon:            favicon,
-		unaryInterceptor:   getDefaultUnaryInterceptor(config.Name),
-		publicMiddleware:   getDefaultPublicMiddleware(config.Version),
+		metrics:            appMetrics,
+		unaryInterceptor:   getDefaultUnaryInterceptor(config.Name, appMetrics),
+		streamInterceptor:  getDefaultStreamInterceptor(config.Name, appMetrics),
+		publicMiddleware:   getDefaultPublicMiddleware(config.Version, appMetrics),
 		publicCloser:       closer.New(syscall.SIGTERM, syscall.SIGINT),
-		customPublicCloser: make(PublicCloserFnMap),
+		health:             health.NewRegistry(),
+		grpcHealth:         grpchealth.NewServer(),
+		customPublicCloser: make(map[string]publicCloser),
+		defaultGraceful:    defaultGraceful(),
+		serverGraceful:     make(map[string]Graceful),
+		adminAuth:          make(map[string]*adminAuth),
+		startTime:          time.Now(),
+
+		customHTTPServerConfig: make(map[string]HTTPServerConfig),
+		tlsReloader:            make(map[string]*certs.Reloader),
+		certReloader:           make(map[string]*certs.Reloader),
 	}
 
 	if err := a.initServers(); err != nil {
@@ -102,27 +149,61 @@ func (a *App) Run(impl ...transport.Service) {
 		descs = append(descs, i.GetDescription())
 	}
 	implDesc := transport.NewCompoundServiceDesc(descs...)
-	implDesc.Apply(transport.WithUnaryInterceptor(grpc_middleware.ChainUnaryServer(a.unaryInterceptor...)))
-	a.runServers(implDesc)
+	a.unaryChain = grpc_middleware.ChainUnaryServer(a.unaryInterceptor...)
+	implDesc.Apply(transport.WithUnaryInterceptor(a.unaryChain))
+	if err := a.runServers(implDesc); err != nil {
+		os.Exit(1)
+	}
+}
+
+// RunWorkers runs App without grpc and public HTTP servers: only admin server (metrics, health, pprof)
+// and workers registered with WithWorker. Listeners of GrpcPort and HttpPort are closed
+func (a *App) RunWorkers() {
+	a.closePublicListeners()
+	if err := a.runServers(nil); err != nil {
+		os.Exit(1)
+	}
+}
+
+// Metrics returns metrics registry of App with basic collectors, collectors added to it are served on /metrics
+func (a *App) Metrics() *metrics.Registry {
+	return a.metrics
+}
+
+// SetServingStatus changes status returned by grpc.health.v1.Health for service, empty name is the whole server.
+// Status is NOT_SERVING for all services after graceful shutdown started and can't be changed.
+func (a *App) SetServingStatus(service string, serving bool) {
+	servingStatus := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		servingStatus = healthpb.HealthCheckResponse_SERVING
+	}
+	a.grpcHealth.SetServingStatus(service, servingStatus)
 }
 
+// GracefulDelay waits DefaultGracefulDelay, servers of App use delay from WithGracefulShutdown
 func GracefulDelay(serviceName string) {
 	logger.Info(logger.App, serviceName+": waiting stop of traffic")
-	time.Sleep(gracefulDelay)
+	time.Sleep(DefaultGracefulDelay)
 	logger.Info(logger.App, serviceName+": shutting down")
 }
 
-func (a *App) runServers(impl *transport.CompoundServiceDesc) {
-	if a.grpcListener != nil {
-		a.grpcServer = grpc.NewServer(grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(a.unaryInterceptor...)))
+func (a *App) runServers(impl *transport.CompoundServiceDesc) error {
+	go a.watchShutdown()
+
+	if a.grpcListener != nil || a.grpcOnHTTPPort || a.grpcWeb != nil {
+		a.grpcServer = grpc.NewServer(a.grpcServerOptions()...)
 		impl.RegisterGRPC(a.grpcServer)
+		a.initGRPCHealth()
 		reflection.Register(a.grpcServer)
+	}
+	if a.grpcListener != nil {
 		a.runGRPC()
 	}
 
+	generated := make(map[string]struct{})
 	if a.httpListener != nil {
 		a.httpServer.Use(a.publicMiddleware...)
-		impl.RegisterHTTP(a.httpServer)
+		impl.RegisterHTTP(&recordingRouter{Router: a.httpServer, routes: generated})
 		a.httpServer.Get("/", func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Add("Content-Type", "text/html")
 			text := a.config.Name + " " + a.config.Version
@@ -132,10 +213,6 @@ func (a *App) runServers(impl *transport.CompoundServiceDesc) {
 			w.Header().Add("Content-Type", "image/x-icon")
 			_, _ = w.Write(a.favicon)
 		})
-		if a.customEnablePprof {
-			logger.Info(logger.App, "PPROF enabled")
-			a.httpServer.Mount("/debug", chiwm.Profiler())
-		}
 		for _, h := range a.customPublicHandler {
 			a.httpServer.MethodFunc(h.Method, h.Pattern, h.NewHandlerFuncWithMiddleware())
 		}
@@ -143,47 +220,90 @@ func (a *App) runServers(impl *transport.CompoundServiceDesc) {
 	}
 
 	if a.httpAdminListener != nil {
+		a.buildInventory(impl, generated)
 		a.initAdminHandlers(impl)
 		a.runAdminHTTP()
 	}
 
-	for name, fn := range a.customPublicCloser {
-		a.publicCloser.Add(name, fn)
+	a.runWorkers()
+
+	for name, c := range a.customPublicCloser {
+		a.publicCloser.AddWithStage(name, c.stage, c.fn)
 	}
+	a.publicCloser.SetTimeout(closer.DefaultFuncTimeout, a.shutdownTimeout())
 
 	// Wait signal and close all resources
 	a.publicCloser.Wait()
+	err := a.publicCloser.CloseAllWithResult()
+	if err != nil {
+		logger.Error(logger.App, "Shutdown failed: %v", err)
+	}
 	// Close all other resources from globalCloser
-	closer.CloseAll()
+	if globalErr := closer.CloseAllWithResult(); globalErr != nil {
+		logger.Error(logger.App, "Shutdown of global resources failed: %v", globalErr)
+		if err == nil {
+			err = globalErr
+		}
+	}
+	if failure := a.failureErr(); failure != nil {
+		logger.Error(logger.App, "App failed: %v", failure)
+		return failure
+	}
+	return err
 }
 
-func getDefaultUnaryInterceptor(appName string) []grpc.UnaryServerInterceptor {
-	errConverters := []errors.ErrorConverter{
+func getErrorConverters(appName string) []errors.ErrorConverter {
+	return []errors.ErrorConverter{
 		validatorerr.Converter(),
 		errhttp.Converter(appName),
 		errgrpc.Converter(appName),
 	}
+}
+
+func newRecoveryHandler(m *metrics.Registry) grpc_recovery.RecoveryHandlerFunc {
+	return func(data interface{}) (err error) {
+		m.CountPanic.Inc()
+		sentry.Panic(data)
+		return nil
+	}
+}
+
+func getDefaultUnaryInterceptor(appName string, m *metrics.Registry) []grpc.UnaryServerInterceptor {
 	return []grpc.UnaryServerInterceptor{
 		grpc_ctxtags.UnaryServerInterceptor(),
 		grpc_prometheus.UnaryServerInterceptor,
-		errmw.NewConvertErrorsServerInterceptor(errConverters, &metrics.CountError),
+		middleware.NewMetricsInterceptor(m),
+		errmw.NewConvertErrorsServerInterceptor(getErrorConverters(appName), &m.CountError),
 		validatormw.NewValidateServerInterceptor(pkgvalidator.New()),
 		middleware.NewLogInterceptor(),
-		grpc_recovery.UnaryServerInterceptor(grpc_recovery.WithRecoveryHandler(func(data interface{}) (err error) {
-			sentry.Panic(data)
-			return nil
-		})),
+		grpc_recovery.UnaryServerInterceptor(grpc_recovery.WithRecoveryHandler(newRecoveryHandler(m))),
+	}
+}
+
+func getDefaultStreamInterceptor(appName string, m *metrics.Registry) []grpc.StreamServerInterceptor {
+	return []grpc.StreamServerInterceptor{
+		grpc_ctxtags.StreamServerInterceptor(),
+		grpc_prometheus.StreamServerInterceptor,
+		middleware.NewStreamFromUnaryInterceptor(middleware.NewMetricsInterceptor(m)),
+		middleware.NewStreamFromUnaryInterceptor(
+			errmw.NewConvertErrorsServerInterceptor(getErrorConverters(appName), &m.CountError),
+		),
+		validatormw.NewValidateStreamServerInterceptor(pkgvalidator.New()),
+		middleware.NewLogStreamInterceptor(),
+		grpc_recovery.StreamServerInterceptor(grpc_recovery.WithRecoveryHandler(newRecoveryHandler(m))),
 	}
 }
 
-func getDefaultPublicMiddleware(appVersion string) []func(http.Handler) http.Handler {
+func getDefaultPublicMiddleware(appVersion string, m *metrics.Registry) []func(http.Handler) http.Handler {
 	ret := make([]func(http.Handler) http.Handler, 0, 10)
 	ret = append(ret, middleware.NewTimingMiddleware()...)
 	ret = append(ret,
 		middleware.NewHeartbeatMiddleware(),
 		middleware.NewCorsMiddleware(),
 		middleware.NewRequestIdMiddleware(),
-		middleware.NewLogMiddleware(),
+		middleware.NewMetricsMiddleware(m),
+		middleware.NewRecoveryMiddlewareWithMetrics(m),
+		middleware.NewLogMiddlewareWithMetrics(m),
 		middleware.NewNoCacheMiddleware(),
 		middleware.NewVersionMiddleware(appVersion),
 	)
@@ -217,7 +337,10 @@ func (a *App) initServers() error {
 		a.httpAdminServer = chi.NewMux()
 	}
 
-	if a.config.Listener.GrpcPort != 0 {
+	if a.config.Listener.GrpcPort != 0 && a.config.Listener.GrpcPort == a.config.Listener.HttpPort {
+		logger.Info(logger.App, "GRPC is served by public HTTP listener at %s:%d", a.config.Listener.Host, a.config.Listener.GrpcPort)
+		a.grpcOnHTTPPort = true
+	} else if a.config.Listener.GrpcPort != 0 {
 		logger.Info(logger.App, "Starting GRPC listener at %s:%d", a.config.Listener.Host, a.config.Listener.GrpcPort)
 		grpcListener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", a.config.Listener.Host, a.config.Listener.GrpcPort))
 		if err != nil {
@@ -229,38 +352,87 @@ func (a *App) initServers() error {
 	return nil
 }
 
+// closePublicListeners releases grpc and public HTTP ports in worker mode
+func (a *App) closePublicListeners() {
+	if a.httpListener != nil {
+		logger.Info(logger.App, "Worker mode: public HTTP listener is closed")
+		_ = a.httpListener.Close()
+		a.httpListener = nil
+	}
+	if a.grpcListener != nil {
+		logger.Info(logger.App, "Worker mode: GRPC listener is closed")
+		_ = a.grpcListener.Close()
+		a.grpcListener = nil
+	}
+	a.grpcOnHTTPPort = false
+	a.grpcWeb = nil
+	if a.httpAdminListener == nil {
+		logger.Info(logger.App, "Worker mode: admin HTTP listener is not configured, metrics and health probes are not available")
+	}
+}
+
 func (a *App) initAdminHandlers(implDesc *transport.CompoundServiceDesc) {
 	urlPrefix := a.adminURLPrefix
+	// documentation is not available in worker mode
+	restDocs := implDesc != nil && a.config.Listener.HttpPort != 0
+	grpcDocs := implDesc != nil && a.config.Listener.GrpcPort != 0
+	docs := a.adminRouter(AdminGroupDocs)
 
 	// table of contents
-	a.httpAdminServer.Get("/docs", func(w http.ResponseWriter, r *http.Request) {
+	docs.Get("/docs", func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, urlPrefix+"/", 301)
 	})
-	a.httpAdminServer.Get("/docs/", func(w http.ResponseWriter, r *http.Request) {
+	docs.Get("/docs/", func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, urlPrefix+"/", 301)
 	})
-	a.httpAdminServer.Get(urlPrefix+"/", func(w http.ResponseWriter, r *http.Request) {
+	docs.Get(urlPrefix+"/", func(w http.ResponseWriter, r *http.Request) {
 		body := "<h1>Table of contents</h1><ul>"
-		if a.config.Listener.HttpPort != 0 {
+		if restDocs {
 			body += `<li><a href="` + urlPrefix + `/docs/rest/">REST documentation</a></li>`
 		}
-		if a.config.Listener.GrpcPort != 0 {
+		if grpcDocs {
 			body += `<li><a href="` + urlPrefix + `/docs/grpc/">GRPC documentation</a></li>`
 		}
+		if a.customEnablePprof {
+			body += `<li><a href="` + urlPrefix + `/debug/pprof/">Profiling</a></li>`
+		}
+		body += `<li><a href="` + urlPrefix + `/info">Build and runtime info</a></li>`
+		body += `<li><a href="` + urlPrefix + `/routes">Routes and RPC</a> (<a href="` + urlPrefix + `/routes?format=json">JSON</a>)</li>`
 		body += `<li><a href="` + urlPrefix + `/metrics">Metrics</a></li>`
+		body += `<li><a href="` + urlPrefix + `/health/live">Liveness probe</a></li>`
+		body += `<li><a href="` + urlPrefix + `/health/ready">Readiness probe</a></li>`
+		body += `<li><a href="` + urlPrefix + `/log/level">Log level</a></li>`
 		body += `</ul>`
 		_, _ = w.Write([]byte(body))
 	})
 
+	// build info and effective config
+	docs.Get("/info", a.infoHandler)
+	// registered routes, rpc and bindings
+	docs.Get("/routes", a.inventoryHandler)
+
 	// metrics
-	a.httpAdminServer.Mount("/metrics", metrics.Metrics())
+	a.adminRouter(AdminGroupMetrics).Mount("/metrics", a.metrics.Handler())
+
+	// health probes
+	healthRouter := a.adminRouter(AdminGroupHealth)
+	healthRouter.Get("/health/live", a.health.LiveHandler())
+	healthRouter.Get("/health/ready", a.health.ReadyHandler())
+
+	// runtime log level and profiling
+	debug := a.adminRouter(AdminGroupDebug)
+	debug.HandleFunc("/log/level", loglevel.Handler())
+	if a.customEnablePprof {
+		logger.Info(logger.App, "PPROF enabled on admin server")
+		debug.Mount("/debug", chiwm.Profiler())
+	}
 
 	// grpc documentation
-	if a.config.Listener.GrpcPort != 0 {
-		a.httpAdminServer.Get("/docs/grpc", func(w http.ResponseWriter, r *http.Request) {
+	if grpcDocs {
+		docs.Get("/docs/grpc", func(w http.ResponseWriter, r *http.Request) {
 			http.Redirect(w, r, urlPrefix+"/docs/grpc/", 301)
 		})
-		a.httpAdminServer.HandleFunc("/docs/grpc/", func(w http.ResponseWriter, r *http.Request) {
+		docs.HandleFunc("/docs/grpc/", func(w http.ResponseWriter, r *http.Request) {
 			filePath := "docs/grpc/index.html"
 			_, err := os.Stat(filePath)
 			if os.IsNotExist(err) {
@@ -284,17 +456,17 @@ doc-grpc: bin-deps ; $(info $(M) generate grpc docs…) @ ## Generate GRPC docum
 	}
 
 	// swagger
-	if a.config.Listener.HttpPort != 0 {
-		a.httpAdminServer.Get("/docs/rest", func(w http.ResponseWriter, r *http.Request) {
+	if restDocs {
+		docs.Get("/docs/rest", func(w http.ResponseWriter, r *http.Request) {
 			http.Redirect(w, r, urlPrefix+"/docs/rest/", 301)
 		})
-		a.httpAdminServer.Mount("/docs/rest/", http.StripPrefix("/docs/rest", swaggerui.NewHTTPHandler()))
-		a.httpAdminServer.Get("/docs/rest/swagger.json", func(w http.ResponseWriter, r *http.Request) {
+		docs.Mount("/docs/rest/", http.StripPrefix("/docs/rest", swaggerui.NewHTTPHandler()))
+		docs.Get("/docs/rest/swagger.json", func(w http.ResponseWriter, r *http.Request) {
 			http.Redirect(w, r, urlPrefix+"/swagger.json", 301)
 		})
 		removeSchemeRE := regexp.MustCompile("^https?://")
 		hostWithoutScheme := removeSchemeRE.ReplaceAllString(a.config.Host, "")
-		a.httpAdminServer.HandleFunc("/swagger.json", func(w http.ResponseWriter, r *http.Request) {
+		docs.HandleFunc("/swagger.json", func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-MimeType", "application/json")
 			o := []swagger.Option{
 				swagger.WithHost(hostWithoutScheme),
@@ -311,6 +483,28 @@ doc-grpc: bin-deps ; $(info $(M) generate grpc docs…) @ ## Generate GRPC docum
 	}
 }
 
+// watchShutdown fails readiness and grpc health before graceful delay, so load balancers stop sending traffic
+func (a *App) watchShutdown() {
+	<-a.publicCloser.Closing()
+	a.health.SetShuttingDown()
+	a.grpcHealth.Shutdown()
+}
+
+// initGRPCHealth marks every registered service as SERVING unless status was set by application
+func (a *App) initGRPCHealth() {
+	names := []string{""}
+	for name := range a.grpcServer.GetServiceInfo() {
+		names = append(names, name)
+	}
+	for _, name := range names {
+		_, err := a.grpcHealth.Check(context.Background(), &healthpb.HealthCheckRequest{Service: name})
+		if status.Code(err) == codes.NotFound {
+			a.grpcHealth.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+		}
+	}
+	healthpb.RegisterHealthServer(a.grpcServer, a.grpcHealth)
+}
+
 func (a *App) runGRPC() {
 	go func() {
 		if err := a.grpcServer.Serve(a.grpcListener); err != nil {
@@ -318,12 +512,7 @@ func (a *App) runGRPC() {
 			a.publicCloser.CloseAll()
 		}
 	}()
-	a.publicCloser.Add("grpc", func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), gracefulTimeout)
-		defer cancel()
-
-		GracefulDelay("grpc")
-
+	a.addServerCloser(ServerGRPC, closer.StageServers, func(ctx context.Context) error {
 		done := make(chan struct{})
 		go func() {
 			a.grpcServer.GracefulStop()
@@ -344,49 +533,49 @@ func (a *App) runGRPC() {
 }
 
 func (a *App) runPublicHTTP() {
-	publicServer := &http.Server{Handler: a.httpServer}
+	handler, mux := a.publicHandler()
+	publicServer := a.newHTTPServer(ServerPublicHTTP, handler)
 	go func() {
-		if err := publicServer.Serve(a.httpListener); err != nil {
+		if err := serveHTTP(publicServer, a.httpListener); err != nil {
 			logger.Info(logger.App, "http.public: %s", err)
 			a.publicCloser.CloseAll()
 		}
 	}()
-	a.publicCloser.Add("http.public", func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), gracefulTimeout)
-		defer cancel()
-
-		GracefulDelay("http.public")
-
+	a.addServerCloser(ServerPublicHTTP, closer.StageServers, func(ctx context.Context) error {
 		publicServer.SetKeepAlivesEnabled(false)
 		if err := publicServer.Shutdown(ctx); err != nil {
 			return errors.Internal.Err(context.Background(), "http.public: error during shutdown").
 				WithLogKV("error", err)
 		}
+		if mux != nil {
+			err := mux.drain(ctx)
+			// closes h2c connections hijacked from http server
+			a.grpcServer.Stop()
+			if err != nil {
+				return errors.Internal.Err(context.Background(), "http.public: grpc calls force stopped").
+					WithLogKV("error", err)
+			}
+		}
 		logger.Info(logger.App, "http.public: gracefully stopped")
 		return nil
 	})
 }
 
 func (a *App) runAdminHTTP() {
-	adminServer := &http.Server{Handler: a.httpAdminServer}
+	adminServer := a.newHTTPServer(ServerAdminHTTP, a.httpAdminServer)
 	go func() {
-		if err := adminServer.Serve(a.httpAdminListener); err != nil {
-			logger.Info(logger.App, "admin.public: %s", err)
+		if err := serveHTTP(adminServer, a.httpAdminListener); err != nil {
+			logger.Info(logger.App, "http.admin: %s", err)
 			a.publicCloser.CloseAll()
 		}
 	}()
-	a.publicCloser.Add("admin.public", func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), gracefulTimeout)
-		defer cancel()
-
-		GracefulDelay("admin.public")
-
+	a.addServerCloser(ServerAdminHTTP, stageAdminServer, func(ctx context.Context) error {
 		adminServer.SetKeepAlivesEnabled(false)
 		if err := adminServer.Shutdown(ctx); err != nil {
-			return errors.Internal.Err(context.Background(), "admin.public: error during shutdown").
+			return errors.Internal.Err(context.Background(), "http.admin: error during shutdown").
 				WithLogKV("error", err)
 		}
-		logger.Info(logger.App, "admin.public: gracefully stopped")
+		logger.Info(logger.App, "http.admin: gracefully stopped")
 		return nil
 	})
 }
@@ -1,17 +1,17 @@
 package app
 
 type Config struct {
-	Name      string
-	Version   string
-	Env       string
-	Host      string
-	HostAdmin string
-	Listener  ConfigListener
+	Name      string         `yaml:"name" json:"name" env:"APP_NAME" validate:"required"`
+	Version   string         `yaml:"version" json:"version" env:"APP_VERSION" default:"dev"`
+	Env       string         `yaml:"env" json:"env" env:"APP_ENV" default:"local"`
+	Host      string         `yaml:"host" json:"host" env:"APP_HOST"`
+	HostAdmin string         `yaml:"host_admin" json:"host_admin" env:"APP_HOST_ADMIN"`
+	Listener  ConfigListener `yaml:"listener" json:"listener"`
 }
 
 type ConfigListener struct {
-	Host          string
-	HttpPort      int32
-	HttpAdminPort int32
-	GrpcPort      int32
+	Host          string `yaml:"host" json:"host" env:"LISTENER_HOST"`
+	HttpPort      int32  `yaml:"http_port" json:"http_port" env:"HTTP_PORT" validate:"gte=0,lte=65535"`
+	HttpAdminPort int32  `yaml:"http_admin_port" json:"http_admin_port" env:"HTTP_ADMIN_PORT" validate:"gte=0,lte=65535"`
+	GrpcPort      int32  `yaml:"grpc_port" json:"grpc_port" env:"GRPC_PORT" validate:"gte=0,lte=65535"`
 }
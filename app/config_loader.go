@@ -0,0 +1,457 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/joho/godotenv"
+	"github.com/sanches1984/gopkg-app/types"
+	pkgvalidator "github.com/sanches1984/gopkg-app/validator"
+	errors "github.com/sanches1984/gopkg-errors"
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigLoader fills config structures from several sources.
+// Precedence from lowest to highest: `default` struct tags, config files (YAML or JSON, in order of adding),
+// .env files (in order of adding), process environment variables.
+type ConfigLoader struct {
+	configFiles    []configFile
+	envFiles       []string
+	envPrefix      string
+	skipProcessEnv bool
+}
+
+type ConfigLoaderOption func(l *ConfigLoader)
+
+type configFile struct {
+	path     string
+	optional bool
+}
+
+// ConfigError aggregates all problems found while loading config
+type ConfigError struct {
+	Issues []ConfigIssue
+}
+
+// ConfigIssue describes one missing or invalid config key
+type ConfigIssue struct {
+	Key    string
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	list := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		list = append(list, issue.Key+": "+issue.Reason)
+	}
+	return "config: " + strings.Join(list, "; ")
+}
+
+func (e *ConfigError) add(key, reason string, args ...interface{}) {
+	e.Issues = append(e.Issues, ConfigIssue{Key: key, Reason: fmt.Sprintf(reason, args...)})
+}
+
+// WithConfigFile adds YAML (.yml, .yaml) or JSON (.json) config files, file must exist
+func WithConfigFile(path ...string) ConfigLoaderOption {
+	return func(l *ConfigLoader) {
+		for _, p := range path {
+			l.configFiles = append(l.configFiles, configFile{path: p})
+		}
+	}
+}
+
+// WithOptionalConfigFile adds YAML or JSON config files which are skipped when not exist
+func WithOptionalConfigFile(path ...string) ConfigLoaderOption {
+	return func(l *ConfigLoader) {
+		for _, p := range path {
+			l.configFiles = append(l.configFiles, configFile{path: p, optional: true})
+		}
+	}
+}
+
+// WithEnvFile adds .env files, missing files are skipped. Values from files don't modify process environment.
+func WithEnvFile(path ...string) ConfigLoaderOption {
+	return func(l *ConfigLoader) {
+		l.envFiles = append(l.envFiles, path...)
+	}
+}
+
+// WithEnvPrefix adds prefix to all keys from `env` tags, for example "BILLING_" gives "BILLING_HTTP_PORT"
+func WithEnvPrefix(prefix string) ConfigLoaderOption {
+	return func(l *ConfigLoader) {
+		l.envPrefix = prefix
+	}
+}
+
+// WithoutProcessEnv disables reading of process environment variables
+func WithoutProcessEnv() ConfigLoaderOption {
+	return func(l *ConfigLoader) {
+		l.skipProcessEnv = true
+	}
+}
+
+func NewConfigLoader(option ...ConfigLoaderOption) *ConfigLoader {
+	l := &ConfigLoader{}
+	for _, o := range option {
+		o(l)
+	}
+	return l
+}
+
+// LoadConfig fills dst (pointer to Config or to a struct embedding Config) and validates it
+func LoadConfig(dst interface{}, option ...ConfigLoaderOption) error {
+	return NewConfigLoader(option...).Load(dst)
+}
+
+// Files returns paths of config and .env files used by loader
+func (l *ConfigLoader) Files() []string {
+	ret := make([]string, 0, len(l.configFiles)+len(l.envFiles))
+	for _, f := range l.configFiles {
+		ret = append(ret, f.path)
+	}
+	return append(ret, l.envFiles...)
+}
+
+// Load fills dst from all sources and validates result. All problems are returned as one *ConfigError.
+func (l *ConfigLoader) Load(dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.Internal.Err(context.Background(), "config: destination should be a pointer to struct").
+			WithLogKV("type", fmt.Sprintf("%T", dst))
+	}
+	rv = rv.Elem()
+
+	cfgErr := &ConfigError{}
+	fields := make([]configField, 0, 16)
+	collectConfigFields(rv, rv.Type().Name(), l.envPrefix, &fields)
+
+	for _, f := range fields {
+		if f.defaultValue == nil {
+			continue
+		}
+		if err := setConfigValue(f.value, *f.defaultValue); err != nil {
+			cfgErr.add(f.name(), "invalid default value %q: %v", *f.defaultValue, err)
+		}
+	}
+
+	for _, file := range l.configFiles {
+		data, err := l.readConfigFile(file)
+		if err != nil {
+			cfgErr.add(file.path, "%v", err)
+			continue
+		}
+		if data != nil {
+			setConfigMap(rv, data, file.path, cfgErr)
+		}
+	}
+
+	env := l.readEnv(cfgErr)
+	for _, f := range fields {
+		if f.envKey == "" {
+			continue
+		}
+		raw, ok := env[f.envKey]
+		if !ok {
+			continue
+		}
+		if err := setConfigValue(f.value, raw); err != nil {
+			cfgErr.add(f.envKey, "invalid value %q: %v", raw, err)
+		}
+	}
+
+	if err := pkgvalidator.New().Struct(dst); err != nil {
+		validationErrs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			cfgErr.add("validation", "%v", err)
+		}
+		for _, fieldErr := range validationErrs {
+			key := fieldErr.StructNamespace()
+			for _, f := range fields {
+				if f.path == key {
+					key = f.name()
+					break
+				}
+			}
+			if fieldErr.Tag() == "required" {
+				cfgErr.add(key, "is required")
+			} else {
+				cfgErr.add(key, "value %v failed '%s' validation", fieldErr.Value(), fieldErr.Tag())
+			}
+		}
+	}
+
+	if len(cfgErr.Issues) > 0 {
+		return cfgErr
+	}
+	return nil
+}
+
+func (l *ConfigLoader) readConfigFile(file configFile) (map[string]interface{}, error) {
+	content, err := ioutil.ReadFile(file.path)
+	if err != nil {
+		if os.IsNotExist(err) && file.optional {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	data := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(file.path)) {
+	case ".yml", ".yaml":
+		raw := make(map[interface{}]interface{})
+		if err := yaml.Unmarshal(content, &raw); err != nil {
+			return nil, err
+		}
+		data = normalizeYAMLMap(raw)
+	case ".json":
+		if err := json.Unmarshal(content, &data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q", filepath.Ext(file.path))
+	}
+	return data, nil
+}
+
+// readEnv merges .env files and process environment, broken .env file is reported but doesn't hide other sources
+func (l *ConfigLoader) readEnv(cfgErr *ConfigError) map[string]string {
+	env := make(map[string]string)
+	for _, path := range l.envFiles {
+		values, err := godotenv.Read(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				cfgErr.add(path, "%v", err)
+			}
+			continue
+		}
+		for k, v := range values {
+			env[k] = v
+		}
+	}
+	if !l.skipProcessEnv {
+		for _, kv := range os.Environ() {
+			if i := strings.IndexByte(kv, '='); i > 0 {
+				env[kv[:i]] = kv[i+1:]
+			}
+		}
+	}
+	return env
+}
+
+type configField struct {
+	value        reflect.Value
+	path         string
+	envKey       string
+	defaultValue *string
+}
+
+func (f configField) name() string {
+	if f.envKey != "" {
+		return f.envKey
+	}
+	return f.path
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func isConfigStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{})
+}
+
+func collectConfigFields(v reflect.Value, path, envPrefix string, fields *[]configField) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		fieldPath := path + "." + sf.Name
+		envKey := sf.Tag.Get("env")
+		if envKey == "-" {
+			continue
+		}
+		if envKey == "" && isConfigStruct(sf.Type) {
+			collectConfigFields(v.Field(i), fieldPath, envPrefix, fields)
+			continue
+		}
+
+		f := configField{value: v.Field(i), path: fieldPath}
+		if envKey != "" {
+			f.envKey = envPrefix + envKey
+		}
+		if def, ok := sf.Tag.Lookup("default"); ok {
+			f.defaultValue = &def
+		}
+		*fields = append(*fields, f)
+	}
+}
+
+// setConfigMap fills struct fields from decoded file, embedded structs without name in tag are inlined
+func setConfigMap(v reflect.Value, data map[string]interface{}, keyPath string, cfgErr *ConfigError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		name := configFileKey(sf)
+		if name == "-" {
+			continue
+		}
+		if sf.Anonymous && name == "" && isConfigStruct(sf.Type) {
+			setConfigMap(v.Field(i), data, keyPath, cfgErr)
+			continue
+		}
+		if name == "" {
+			name = types.CamelToSnakeCase(sf.Name)
+		}
+		raw, ok := data[name]
+		if !ok || raw == nil {
+			continue
+		}
+		key := keyPath + ":" + name
+		if isConfigStruct(sf.Type) {
+			nested, ok := raw.(map[string]interface{})
+			if !ok {
+				cfgErr.add(key, "object expected, got %T", raw)
+				continue
+			}
+			setConfigMap(v.Field(i), nested, key, cfgErr)
+			continue
+		}
+		if list, ok := raw.([]interface{}); ok {
+			if err := setConfigList(v.Field(i), list); err != nil {
+				cfgErr.add(key, "invalid list: %v", err)
+			}
+			continue
+		}
+		value := configString(raw)
+		if err := setConfigValue(v.Field(i), value); err != nil {
+			cfgErr.add(key, "invalid value %q: %v", value, err)
+		}
+	}
+}
+
+// setConfigList fills slice item by item, so items from file may contain commas
+func setConfigList(v reflect.Value, list []interface{}) error {
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("%s is not a list", v.Type())
+	}
+	slice := reflect.MakeSlice(v.Type(), len(list), len(list))
+	for i, item := range list {
+		if err := setConfigValue(slice.Index(i), configString(item)); err != nil {
+			return err
+		}
+	}
+	v.Set(slice)
+	return nil
+}
+
+// configString formats scalar from decoded file, JSON numbers are float64 and should not become 1e+06
+func configString(raw interface{}) string {
+	if f, ok := raw.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(raw)
+}
+
+func configFileKey(sf reflect.StructField) string {
+	for _, tagName := range []string{"yaml", "json"} {
+		if tag, ok := sf.Tag.Lookup(tagName); ok {
+			return strings.Split(tag, ",")[0]
+		}
+	}
+	return ""
+}
+
+func normalizeYAMLMap(in map[interface{}]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(in))
+	for k, v := range in {
+		out[fmt.Sprint(k)] = normalizeYAMLValue(v)
+	}
+	return out
+}
+
+func normalizeYAMLValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		return normalizeYAMLMap(val)
+	case []interface{}:
+		for i := range val {
+			val[i] = normalizeYAMLValue(val[i])
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+func setConfigValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		parts := []string{}
+		if strings.TrimSpace(raw) != "" {
+			parts = strings.Split(raw, ",")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setConfigValue(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Ptr:
+		ptr := reflect.New(v.Type().Elem())
+		if err := setConfigValue(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(ptr)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testServiceConfig struct {
+	Config   `yaml:",inline"`
+	DSN      string        `yaml:"dsn" env:"DB_DSN" validate:"required"`
+	Timeout  time.Duration `yaml:"timeout" env:"TIMEOUT" default:"5s"`
+	Origins  []string      `yaml:"origins" env:"ORIGINS"`
+	MaxConns int           `yaml:"max_conns" env:"MAX_CONNS" default:"10" validate:"gte=1"`
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigLoader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	yamlFile := writeTestFile(t, dir, "config.yaml", `
+name: billing
+env: prod
+dsn: postgres://file
+origins: [a.com, b.com]
+listener:
+  http_port: 8080
+  grpc_port: 8082
+`)
+	jsonFile := writeTestFile(t, dir, "config.json", `{"max_conns": 20, "listener": {"http_admin_port": 8084}}`)
+	envFile := writeTestFile(t, dir, ".env", "DB_DSN=postgres://dotenv\nAPP_ENV=stage\n")
+
+	t.Run("Precedence", func(t *testing.T) {
+		_ = os.Setenv("TEST_APP_ENV", "dev")
+		defer os.Unsetenv("TEST_APP_ENV")
+
+		var cfg testServiceConfig
+		err := LoadConfig(&cfg,
+			WithConfigFile(yamlFile, jsonFile),
+			WithEnvFile(envFile),
+			WithEnvPrefix("TEST_"),
+		)
+		assert.Nil(t, err)
+		assert.Equal(t, "billing", cfg.Name)
+		assert.Equal(t, "dev", cfg.Env)
+		assert.Equal(t, "postgres://file", cfg.DSN)
+		assert.Equal(t, int32(8080), cfg.Listener.HttpPort)
+		assert.Equal(t, int32(8084), cfg.Listener.HttpAdminPort)
+		assert.Equal(t, int32(8082), cfg.Listener.GrpcPort)
+		assert.Equal(t, 20, cfg.MaxConns)
+		assert.Equal(t, 5*time.Second, cfg.Timeout)
+		assert.Equal(t, []string{"a.com", "b.com"}, cfg.Origins)
+	})
+
+	t.Run("EnvFile", func(t *testing.T) {
+		var cfg testServiceConfig
+		err := LoadConfig(&cfg, WithConfigFile(yamlFile), WithEnvFile(envFile), WithoutProcessEnv())
+		assert.Nil(t, err)
+		assert.Equal(t, "stage", cfg.Env)
+		assert.Equal(t, "postgres://dotenv", cfg.DSN)
+	})
+
+	t.Run("AggregatedError", func(t *testing.T) {
+		badFile := writeTestFile(t, dir, "bad.yaml", "max_conns: 0\nlistener:\n  http_port: 70000\n")
+		_ = os.Setenv("TIMEOUT", "soon")
+		defer os.Unsetenv("TIMEOUT")
+
+		var cfg testServiceConfig
+		err := LoadConfig(&cfg, WithConfigFile(badFile), WithOptionalConfigFile(filepath.Join(dir, "missing.yaml")))
+		cfgErr, ok := err.(*ConfigError)
+		if !assert.True(t, ok) {
+			return
+		}
+		keys := make([]string, 0, len(cfgErr.Issues))
+		for _, issue := range cfgErr.Issues {
+			keys = append(keys, issue.Key)
+		}
+		assert.ElementsMatch(t, []string{"TIMEOUT", "APP_NAME", "HTTP_PORT", "DB_DSN", "MAX_CONNS"}, keys)
+	})
+
+	t.Run("ListWithCommas", func(t *testing.T) {
+		listFile := writeTestFile(t, dir, "list.yaml", "name: billing\ndsn: postgres://file\norigins: [\"a.com,b.com\", c.com]\n")
+
+		var cfg testServiceConfig
+		err := LoadConfig(&cfg, WithConfigFile(listFile), WithoutProcessEnv())
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a.com,b.com", "c.com"}, cfg.Origins)
+	})
+
+	t.Run("BrokenEnvFile", func(t *testing.T) {
+		_ = os.Setenv("DB_DSN", "postgres://env")
+		defer os.Unsetenv("DB_DSN")
+
+		var cfg testServiceConfig
+		err := LoadConfig(&cfg, WithConfigFile(yamlFile), WithEnvFile(dir))
+		cfgErr, ok := err.(*ConfigError)
+		if !assert.True(t, ok) {
+			return
+		}
+		assert.Len(t, cfgErr.Issues, 1)
+		assert.Equal(t, dir, cfgErr.Issues[0].Key)
+		assert.Equal(t, "postgres://env", cfg.DSN)
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		var cfg Config
+		err := LoadConfig(&cfg, WithConfigFile(filepath.Join(dir, "missing.yaml")), WithoutProcessEnv())
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,213 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	errors "github.com/sanches1984/gopkg-errors"
+	logger "github.com/sanches1984/gopkg-logger"
+)
+
+// configReloadDelay merges events of one file save, editors write file in several steps
+const configReloadDelay = 100 * time.Millisecond
+
+// ConfigWatcher reloads config on SIGHUP and on change of config files of loader.
+// New config is loaded into new value of the same type and validated, invalid config is rejected
+// and current config is kept. Subscribers are called in order of subscription after successful reload.
+type ConfigWatcher struct {
+	loader *ConfigLoader
+	typ    reflect.Type
+
+	mu          sync.RWMutex
+	current     interface{}
+	subscribers []reflect.Value
+	errors      prometheus.Counter
+
+	reloadMu sync.Mutex
+	watcher  *fsnotify.Watcher
+	signals  chan os.Signal
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewConfigWatcher creates watcher of config loaded by loader, cfg is pointer to loaded config
+// (the same as passed to ConfigLoader.Load). Watching starts with Start.
+func NewConfigWatcher(loader *ConfigLoader, cfg interface{}) (*ConfigWatcher, error) {
+	rv := reflect.ValueOf(cfg)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, errors.Internal.Err(context.Background(), "config: watched config should be a pointer to struct").
+			WithLogKV("type", fmt.Sprintf("%T", cfg))
+	}
+	return &ConfigWatcher{
+		loader:  loader,
+		typ:     rv.Type(),
+		current: cfg,
+		done:    make(chan struct{}),
+	}, nil
+}
+
+// Current returns pointer to current config, returned value must not be modified
+func (w *ConfigWatcher) Current() interface{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Subscribe adds callback with signature func(cfg *T) or func(old, new *T), where *T is type of watched config
+func (w *ConfigWatcher) Subscribe(fn interface{}) error {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func {
+		return errors.Internal.Err(context.Background(), "config: subscriber should be a function").
+			WithLogKV("type", fmt.Sprintf("%T", fn))
+	}
+	ft := fv.Type()
+	valid := ft.NumOut() == 0 && ft.NumIn() >= 1 && ft.NumIn() <= 2
+	for i := 0; valid && i < ft.NumIn(); i++ {
+		valid = ft.In(i) == w.typ
+	}
+	if !valid {
+		return errors.Internal.Err(context.Background(), "config: invalid subscriber signature").
+			WithLogKV("type", ft.String(), "config", w.typ.String())
+	}
+
+	w.mu.Lock()
+	w.subscribers = append(w.subscribers, fv)
+	w.mu.Unlock()
+	return nil
+}
+
+// setErrorCounter sets counter of rejected reloads
+func (w *ConfigWatcher) setErrorCounter(counter prometheus.Counter) {
+	w.mu.Lock()
+	w.errors = counter
+	w.mu.Unlock()
+}
+
+// Reload loads and validates config, on success replaces current config and notifies subscribers
+func (w *ConfigWatcher) Reload() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	next := reflect.New(w.typ.Elem())
+	if err := w.loader.Load(next.Interface()); err != nil {
+		w.mu.RLock()
+		if w.errors != nil {
+			w.errors.Inc()
+		}
+		w.mu.RUnlock()
+		return err
+	}
+
+	w.mu.Lock()
+	old := reflect.ValueOf(w.current)
+	w.current = next.Interface()
+	subscribers := append([]reflect.Value{}, w.subscribers...)
+	w.mu.Unlock()
+
+	for _, fn := range subscribers {
+		w.notify(fn, old, next)
+	}
+	return nil
+}
+
+// notify calls subscriber, panic of one subscriber doesn't stop others
+func (w *ConfigWatcher) notify(fn, old, next reflect.Value) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error(logger.App, "config: subscriber %s panicked: %v", fn.Type(), r)
+		}
+	}()
+	if fn.Type().NumIn() == 1 {
+		fn.Call([]reflect.Value{next})
+		return
+	}
+	fn.Call([]reflect.Value{old, next})
+}
+
+// Start watches SIGHUP and directories of config files
+func (w *ConfigWatcher) Start() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Internal.ErrWrap(context.Background(), "config: can't create watcher", err)
+	}
+	files := make(map[string]struct{})
+	dirs := make(map[string]struct{})
+	for _, file := range w.loader.Files() {
+		files[filepath.Clean(file)] = struct{}{}
+		dirs[filepath.Dir(file)] = struct{}{}
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return errors.Internal.ErrWrap(context.Background(), "config: can't watch directory", err).
+				WithLogKV("dir", dir)
+		}
+	}
+	w.watcher = watcher
+	w.signals = make(chan os.Signal, 1)
+	signal.Notify(w.signals, syscall.SIGHUP)
+	go w.watch(files)
+	return nil
+}
+
+// Close stops watching
+func (w *ConfigWatcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		if w.signals != nil {
+			signal.Stop(w.signals)
+		}
+		if w.watcher != nil {
+			err = w.watcher.Close()
+		}
+	})
+	return err
+}
+
+func (w *ConfigWatcher) watch(files map[string]struct{}) {
+	timer := time.NewTimer(configReloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.signals:
+			w.reload("SIGHUP")
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			// own files and kubernetes configmap "..data" symlink swap trigger reload
+			if _, own := files[filepath.Clean(event.Name)]; !own && filepath.Base(event.Name) != "..data" {
+				continue
+			}
+			timer.Reset(configReloadDelay)
+		case <-timer.C:
+			w.reload("file change")
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Error(logger.App, "config: watcher error: %v", err)
+		}
+	}
+}
+
+func (w *ConfigWatcher) reload(reason string) {
+	if err := w.Reload(); err != nil {
+		logger.Error(logger.App, "config: reload on %s rejected, current config is kept: %v", reason, err)
+		return
+	}
+	logger.Info(logger.App, "config: reloaded on %s", reason)
+}
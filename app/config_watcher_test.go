@@ -0,0 +1,90 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sanches1984/gopkg-app/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+type watchedConfig struct {
+	Config    `yaml:",inline"`
+	RateLimit int `yaml:"rate_limit" validate:"gte=1"`
+}
+
+type signalConfig struct {
+	Limit int `default:"5"`
+}
+
+func TestConfigWatcher(t *testing.T) {
+	m := metrics.NewRegistry("test")
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "config.yml")
+	write := func(content string) {
+		assert.Nil(t, ioutil.WriteFile(file, []byte(content), 0600))
+	}
+	write("name: test\nrate_limit: 10\n")
+
+	loader := NewConfigLoader(WithConfigFile(file), WithoutProcessEnv())
+	cfg := &watchedConfig{}
+	assert.Nil(t, loader.Load(cfg))
+
+	w, err := NewConfigWatcher(loader, cfg)
+	assert.Nil(t, err)
+	w.setErrorCounter(m.CountConfigReloadError)
+	_, err = NewConfigWatcher(loader, *cfg)
+	assert.NotNil(t, err)
+
+	assert.NotNil(t, w.Subscribe(func(cfg watchedConfig) {}))
+	assert.NotNil(t, w.Subscribe(func(cfg *Config) {}))
+	assert.NotNil(t, w.Subscribe(func(cfg *watchedConfig) error { return nil }))
+
+	var mu sync.Mutex
+	var limits []int
+	assert.Nil(t, w.Subscribe(func(old, new *watchedConfig) {
+		mu.Lock()
+		defer mu.Unlock()
+		limits = append(limits, old.RateLimit, new.RateLimit)
+	}))
+	assert.Nil(t, w.Subscribe(func(cfg *watchedConfig) { panic("subscriber") }))
+
+	assert.Nil(t, w.Start())
+	defer w.Close()
+	current := func() int { return w.Current().(*watchedConfig).RateLimit }
+
+	t.Run("FileChange", func(t *testing.T) {
+		write("name: test\nrate_limit: 20\n")
+		assert.Eventually(t, func() bool { return current() == 20 }, time.Second, 10*time.Millisecond)
+		mu.Lock()
+		assert.Equal(t, []int{10, 20}, limits[:2])
+		mu.Unlock()
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		errors := testutil.ToFloat64(m.CountConfigReloadError)
+		write("name: test\nrate_limit: 0\n")
+		assert.NotNil(t, w.Reload())
+		assert.Equal(t, 20, current())
+		assert.True(t, testutil.ToFloat64(m.CountConfigReloadError) > errors)
+	})
+
+	t.Run("SIGHUP", func(t *testing.T) {
+		cfg := &signalConfig{}
+		w, err := NewConfigWatcher(NewConfigLoader(WithoutProcessEnv()), cfg)
+		assert.Nil(t, err)
+		assert.Nil(t, w.Start())
+		defer w.Close()
+
+		assert.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+		assert.Eventually(t, func() bool { return w.Current().(*signalConfig).Limit == 5 }, time.Second, 10*time.Millisecond)
+	})
+}
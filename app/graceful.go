@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/sanches1984/gopkg-app/closer"
+	errors "github.com/sanches1984/gopkg-errors"
+	logger "github.com/sanches1984/gopkg-logger"
+)
+
+const (
+	DefaultGracefulDelay   = 3 * time.Second
+	DefaultGracefulTimeout = 10 * time.Second
+
+	// Server names for WithServerGracefulShutdown
+	ServerGRPC       = "grpc"
+	ServerPublicHTTP = "http.public"
+	ServerAdminHTTP  = "http.admin"
+
+	// admin server is stopped after public servers, so probes and metrics are available while traffic drains
+	stageAdminServer = closer.StageServers + 50
+)
+
+// Graceful shutdown settings: delay waits stop of incoming traffic, timeout limits draining of active requests
+type Graceful struct {
+	Delay   time.Duration
+	Timeout time.Duration
+}
+
+func defaultGraceful() Graceful {
+	return Graceful{Delay: DefaultGracefulDelay, Timeout: DefaultGracefulTimeout}
+}
+
+// newGraceful rejects zero timeout, it would force stop servers without draining
+func newGraceful(delay, timeout time.Duration) (Graceful, error) {
+	if delay < 0 || timeout <= 0 {
+		return Graceful{}, errors.Internal.Err(context.Background(), "Invalid graceful shutdown settings").
+			WithPayloadKV("delay", delay.String(), "timeout", timeout.String())
+	}
+	return Graceful{Delay: delay, Timeout: timeout}, nil
+}
+
+// graceful returns settings of server, admin server doesn't wait traffic stop unless overridden
+func (a *App) graceful(server string) Graceful {
+	if g, ok := a.serverGraceful[server]; ok {
+		return g
+	}
+	if server == ServerAdminHTTP {
+		return Graceful{Timeout: a.defaultGraceful.Timeout}
+	}
+	return a.defaultGraceful
+}
+
+// addServerCloser registers closer which waits traffic stop and calls shutdown with graceful timeout
+func (a *App) addServerCloser(server string, stage closer.Stage, shutdown func(ctx context.Context) error) {
+	g := a.graceful(server)
+	// extra second lets shutdown report force stop before closer timeout
+	a.publicCloser.AddContext(server, stage, g.Delay+g.Timeout+time.Second, func(ctx context.Context) error {
+		if g.Delay > 0 {
+			logger.Info(logger.App, server+": waiting stop of traffic")
+			select {
+			case <-time.After(g.Delay):
+			case <-ctx.Done():
+			}
+		}
+		logger.Info(logger.App, server+": shutting down")
+
+		ctx, cancel := context.WithTimeout(ctx, g.Timeout)
+		defer cancel()
+		return shutdown(ctx)
+	})
+}
+
+// shutdownTimeout returns deadline for all closers including servers and workers draining one after another
+func (a *App) shutdownTimeout() time.Duration {
+	var public time.Duration
+	for _, server := range []string{ServerGRPC, ServerPublicHTTP} {
+		g := a.graceful(server)
+		if g.Delay+g.Timeout > public {
+			public = g.Delay + g.Timeout
+		}
+	}
+	admin := a.graceful(ServerAdminHTTP)
+	total := closer.DefaultTimeout + public + admin.Delay + admin.Timeout
+	if len(a.workers) > 0 {
+		total += a.defaultGraceful.Timeout
+	}
+	return total
+}
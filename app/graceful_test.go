@@ -0,0 +1,29 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraceful(t *testing.T) {
+	t.Run("Options", func(t *testing.T) {
+		a := &App{defaultGraceful: defaultGraceful(), serverGraceful: make(map[string]Graceful)}
+		assert.Nil(t, WithGracefulShutdown(0, 5*time.Second)(a))
+		assert.Nil(t, WithServerGracefulShutdown(ServerGRPC, time.Second, 2*time.Second)(a))
+		assert.Equal(t, Graceful{Timeout: 5 * time.Second}, a.graceful(ServerPublicHTTP))
+		assert.Equal(t, Graceful{Delay: time.Second, Timeout: 2 * time.Second}, a.graceful(ServerGRPC))
+		assert.Equal(t, Graceful{Timeout: 5 * time.Second}, a.graceful(ServerAdminHTTP))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		a := &App{defaultGraceful: defaultGraceful(), serverGraceful: make(map[string]Graceful)}
+		assert.NotNil(t, WithGracefulShutdown(5*time.Second, 0)(a))
+		assert.NotNil(t, WithGracefulShutdown(-time.Second, time.Second)(a))
+		assert.NotNil(t, WithServerGracefulShutdown(ServerAdminHTTP, time.Second, 0)(a))
+		assert.NotNil(t, WithServerGracefulShutdown("admin.public", time.Second, time.Second)(a))
+		assert.Equal(t, defaultGraceful(), a.defaultGraceful)
+		assert.Empty(t, a.serverGraceful)
+	})
+}
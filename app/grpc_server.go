@@ -0,0 +1,74 @@
+package app
+
+import (
+	"time"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+)
+
+// GRPCServerConfig contains limits of grpc server, zero values keep grpc defaults
+type GRPCServerConfig struct {
+	MaxRecvMsgSize       int
+	MaxSendMsgSize       int
+	MaxConcurrentStreams uint32
+
+	// KeepaliveTime and KeepaliveTimeout control server side pings of idle connections
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+	// KeepaliveMinTime is minimal interval of client pings, connections of clients pinging more often are closed
+	KeepaliveMinTime             time.Duration
+	KeepalivePermitWithoutStream bool
+
+	// MaxConnectionIdle, MaxConnectionAge and MaxConnectionAgeGrace recycle connections,
+	// so clients rebalance between instances
+	MaxConnectionIdle     time.Duration
+	MaxConnectionAge      time.Duration
+	MaxConnectionAgeGrace time.Duration
+}
+
+func (c GRPCServerConfig) serverOptions() []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if c.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(c.MaxSendMsgSize))
+	}
+	if c.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(c.MaxConcurrentStreams))
+	}
+
+	params := keepalive.ServerParameters{
+		Time:                  c.KeepaliveTime,
+		Timeout:               c.KeepaliveTimeout,
+		MaxConnectionIdle:     c.MaxConnectionIdle,
+		MaxConnectionAge:      c.MaxConnectionAge,
+		MaxConnectionAgeGrace: c.MaxConnectionAgeGrace,
+	}
+	if params != (keepalive.ServerParameters{}) {
+		opts = append(opts, grpc.KeepaliveParams(params))
+	}
+	if c.KeepaliveMinTime > 0 || c.KeepalivePermitWithoutStream {
+		opts = append(opts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             c.KeepaliveMinTime,
+			PermitWithoutStream: c.KeepalivePermitWithoutStream,
+		}))
+	}
+	return opts
+}
+
+// grpcServerOptions returns interceptors, config, TLS and custom options, custom options are the last to override others
+func (a *App) grpcServerOptions() []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(a.unaryInterceptor...)),
+		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(a.streamInterceptor...)),
+	}
+	opts = append(opts, a.grpcServerConfig.serverOptions()...)
+	if reloader, ok := a.tlsReloader[ServerGRPC]; ok {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(reloader.ServerConfig())))
+	}
+	return append(opts, a.grpcServerOption...)
+}
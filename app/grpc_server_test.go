@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// startTestGRPC serves health service with options of App and returns connected client
+func startTestGRPC(t *testing.T, a *App) (*grpc.ClientConn, func()) {
+	server := grpc.NewServer(a.grpcServerOptions()...)
+	healthpb.RegisterHealthServer(server, grpchealth.NewServer())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve(listener) }()
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, func() {
+		_ = conn.Close()
+		server.Stop()
+	}
+}
+
+// checkLarge calls health check with service name of size bytes, unknown service returns NotFound
+func checkLarge(conn *grpc.ClientConn, size int) codes.Code {
+	_, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: strings.Repeat("x", size),
+	})
+	return status.Code(err)
+}
+
+func TestGRPCServerConfig(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		assert.Len(t, GRPCServerConfig{}.serverOptions(), 0)
+	})
+
+	t.Run("MaxRecvMsgSize", func(t *testing.T) {
+		conn, stop := startTestGRPC(t, &App{grpcServerConfig: GRPCServerConfig{MaxRecvMsgSize: 1024}})
+		defer stop()
+		assert.Equal(t, codes.NotFound, checkLarge(conn, 512))
+		assert.Equal(t, codes.ResourceExhausted, checkLarge(conn, 2048))
+	})
+
+	t.Run("MaxConnectionIdle", func(t *testing.T) {
+		conn, stop := startTestGRPC(t, &App{grpcServerConfig: GRPCServerConfig{MaxConnectionIdle: 100 * time.Millisecond}})
+		defer stop()
+		assert.Equal(t, codes.NotFound, checkLarge(conn, 1))
+		assert.Equal(t, connectivity.Ready, conn.GetState())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		assert.True(t, conn.WaitForStateChange(ctx, connectivity.Ready), "idle connection should be closed by server")
+	})
+
+	t.Run("MaxConnectionAge", func(t *testing.T) {
+		conn, stop := startTestGRPC(t, &App{grpcServerConfig: GRPCServerConfig{
+			MaxConnectionAge:      100 * time.Millisecond,
+			MaxConnectionAgeGrace: 100 * time.Millisecond,
+		}})
+		defer stop()
+		assert.Equal(t, codes.NotFound, checkLarge(conn, 1))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		assert.True(t, conn.WaitForStateChange(ctx, connectivity.Ready), "old connection should be closed by server")
+	})
+
+	t.Run("DefaultKeepsConnection", func(t *testing.T) {
+		conn, stop := startTestGRPC(t, &App{})
+		defer stop()
+		assert.Equal(t, codes.NotFound, checkLarge(conn, 1))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		assert.False(t, conn.WaitForStateChange(ctx, connectivity.Ready))
+	})
+
+	t.Run("CustomOverridesConfig", func(t *testing.T) {
+		conn, stop := startTestGRPC(t, &App{
+			grpcServerConfig: GRPCServerConfig{MaxRecvMsgSize: 1024},
+			grpcServerOption: []grpc.ServerOption{grpc.MaxRecvMsgSize(4096)},
+		})
+		defer stop()
+		assert.Equal(t, codes.NotFound, checkLarge(conn, 2048))
+		assert.Equal(t, codes.ResourceExhausted, checkLarge(conn, 8192))
+	})
+}
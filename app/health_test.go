@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sanches1984/gopkg-app/closer"
+	"github.com/sanches1984/gopkg-app/health"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCHealth(t *testing.T) {
+	a := &App{
+		grpcServer:   grpc.NewServer(),
+		publicCloser: closer.New(),
+		health:       health.NewRegistry(),
+		grpcHealth:   grpchealth.NewServer(),
+	}
+	a.grpcServer.RegisterService(&grpc.ServiceDesc{ServiceName: "test.Echo", HandlerType: (*interface{})(nil)}, struct{}{})
+	a.grpcServer.RegisterService(&grpc.ServiceDesc{ServiceName: "test.Admin", HandlerType: (*interface{})(nil)}, struct{}{})
+	a.SetServingStatus("test.Admin", false)
+	a.initGRPCHealth()
+	go a.watchShutdown()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	go func() { _ = a.grpcServer.Serve(listener) }()
+	defer a.grpcServer.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+	check := func(service string) (healthpb.HealthCheckResponse_ServingStatus, codes.Code) {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return healthpb.HealthCheckResponse_UNKNOWN, status.Code(err)
+		}
+		return resp.Status, codes.OK
+	}
+
+	t.Run("Registered", func(t *testing.T) {
+		s, _ := check("")
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, s)
+		s, _ = check("test.Echo")
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, s)
+		s, _ = check("test.Admin")
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, s)
+		_, code := check("test.Unknown")
+		assert.Equal(t, codes.NotFound, code)
+	})
+
+	t.Run("SetServingStatus", func(t *testing.T) {
+		a.SetServingStatus("test.Admin", true)
+		s, _ := check("test.Admin")
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, s)
+	})
+
+	t.Run("Shutdown", func(t *testing.T) {
+		a.publicCloser.CloseAll()
+		for _, service := range []string{"", "test.Echo", "test.Admin"} {
+			assert.Eventually(t, func() bool {
+				s, _ := check(service)
+				return s == healthpb.HealthCheckResponse_NOT_SERVING
+			}, time.Second, 10*time.Millisecond, service)
+		}
+		a.SetServingStatus("test.Echo", true)
+		s, _ := check("test.Echo")
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, s)
+	})
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sanches1984/gopkg-app/certs"
+	"github.com/sanches1984/gopkg-app/closer"
+)
+
+// HTTPServerConfig hardens http.Server of listener, zero values keep net/http defaults
+type HTTPServerConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	DisableHTTP2      bool
+}
+
+// DefaultHTTPServerConfig protects from slow clients without limiting long responses
+func DefaultHTTPServerConfig() HTTPServerConfig {
+	return HTTPServerConfig{
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+	}
+}
+
+// TLSConfig contains PEM files, they are reloaded on change without restart
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mTLS, clients must present certificate signed by this CA
+	ClientCAFile string
+}
+
+func (a *App) httpServerConfig(server string) HTTPServerConfig {
+	if cfg, ok := a.customHTTPServerConfig[server]; ok {
+		return cfg
+	}
+	return DefaultHTTPServerConfig()
+}
+
+func (a *App) newHTTPServer(server string, handler http.Handler) *http.Server {
+	cfg := a.httpServerConfig(server)
+	srv := &http.Server{
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+	if reloader, ok := a.tlsReloader[server]; ok {
+		srv.TLSConfig = reloader.ServerConfig()
+	}
+	if cfg.DisableHTTP2 {
+		srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+	return srv
+}
+
+func serveHTTP(srv *http.Server, listener net.Listener) error {
+	if srv.TLSConfig != nil {
+		// certificate is provided by TLSConfig.GetCertificate
+		return srv.ServeTLS(listener, "", "")
+	}
+	return srv.Serve(listener)
+}
+
+// setTLS attaches certificate reloader to server, reloaders are shared between servers with the same files
+func (a *App) setTLS(server string, cfg TLSConfig) error {
+	key := certs.Key(cfg.CertFile, cfg.KeyFile, cfg.ClientCAFile)
+	reloader, ok := a.certReloader[key]
+	if !ok {
+		var err error
+		reloader, err = certs.NewReloader(cfg.CertFile, cfg.KeyFile, cfg.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		a.certReloader[key] = reloader
+		a.publicCloser.AddWithStage("certs:"+key, closer.StageClients, reloader.Close)
+	}
+	a.tlsReloader[server] = reloader
+	return nil
+}
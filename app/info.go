@@ -0,0 +1,174 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/sanches1984/gopkg-app/types"
+)
+
+// GitCommit and BuildTime are set on build:
+// go build -ldflags "-X github.com/sanches1984/gopkg-app/app.GitCommit=$(git rev-parse HEAD) -X github.com/sanches1984/gopkg-app/app.BuildTime=$(date -u +%FT%TZ)"
+var (
+	GitCommit string
+	BuildTime string
+)
+
+const maskedValue = "******"
+
+// secretFieldRE matches names of config fields which values are masked, tag secret:"true" masks any field
+var secretFieldRE = regexp.MustCompile(`(?i)(password|passwd|secret|token|key|dsn|credential)`)
+
+// Info is returned by /info of admin server
+type Info struct {
+	Name         string            `json:"name"`
+	Version      string            `json:"version"`
+	Env          string            `json:"env"`
+	GitCommit    string            `json:"git_commit"`
+	BuildTime    string            `json:"build_time"`
+	GoVersion    string            `json:"go_version"`
+	StartTime    time.Time         `json:"start_time"`
+	Uptime       string            `json:"uptime"`
+	Dependencies map[string]string `json:"dependencies"`
+	Config       interface{}       `json:"config"`
+}
+
+func (a *App) info() Info {
+	cfg := a.infoConfig
+	if cfg == nil && a.configWatcher != nil {
+		cfg = a.configWatcher.Current()
+	}
+	if cfg == nil {
+		cfg = a.config
+	}
+	return Info{
+		Name:         a.config.Name,
+		Version:      a.config.Version,
+		Env:          a.config.Env,
+		GitCommit:    GitCommit,
+		BuildTime:    BuildTime,
+		GoVersion:    runtime.Version(),
+		StartTime:    a.startTime,
+		Uptime:       time.Since(a.startTime).Truncate(time.Second).String(),
+		Dependencies: dependencies(),
+		Config:       maskConfig(reflect.ValueOf(cfg)),
+	}
+}
+
+func (a *App) infoHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(a.info())
+}
+
+// buildInfoLabels are labels of build_info gauge
+func buildInfoLabels(config Config) map[string]string {
+	return map[string]string{
+		"name":       config.Name,
+		"version":    config.Version,
+		"env":        config.Env,
+		"git_commit": GitCommit,
+		"build_time": BuildTime,
+		"go_version": runtime.Version(),
+	}
+}
+
+// dependencies returns versions of modules, replaced modules have version of replacement
+func dependencies() map[string]string {
+	ret := make(map[string]string)
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ret
+	}
+	for _, dep := range buildInfo.Deps {
+		version := dep.Version
+		if dep.Replace != nil {
+			version = dep.Replace.Path + " " + dep.Replace.Version
+		}
+		ret[dep.Path] = version
+	}
+	return ret
+}
+
+// maskConfig converts config to map with keys of config files, values of secret fields are masked
+func maskConfig(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if !isConfigStruct(v.Type()) {
+			return v.Interface()
+		}
+		ret := make(map[string]interface{})
+		maskStruct(v, ret)
+		return ret
+	case reflect.Slice, reflect.Array:
+		ret := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			ret = append(ret, maskConfig(v.Index(i)))
+		}
+		return ret
+	case reflect.Map:
+		ret := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if secretFieldRE.MatchString(key) {
+				ret[key] = maskedValue
+				continue
+			}
+			ret[key] = maskConfig(iter.Value())
+		}
+		return ret
+	case reflect.Int64:
+		if v.Type() == durationType {
+			return time.Duration(v.Int()).String()
+		}
+	}
+	return v.Interface()
+}
+
+func maskStruct(v reflect.Value, dst map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		name := configFileKey(sf)
+		if name == "-" {
+			continue
+		}
+		if sf.Anonymous && name == "" && isConfigStruct(sf.Type) {
+			maskStruct(v.Field(i), dst)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = types.CamelToSnakeCase(sf.Name)
+		}
+		if sf.Tag.Get("secret") == "true" || secretFieldRE.MatchString(sf.Name) {
+			if !v.Field(i).IsZero() {
+				dst[name] = maskedValue
+			} else {
+				dst[name] = ""
+			}
+			continue
+		}
+		dst[name] = maskConfig(v.Field(i))
+	}
+}
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskConfig(t *testing.T) {
+	cfg := &testServiceConfig{
+		Config:  Config{Name: "app", Listener: ConfigListener{HttpPort: 8080}},
+		DSN:     "postgres://user:password@db/app",
+		Timeout: 5 * time.Second,
+		Origins: []string{"https://example.com"},
+	}
+	masked := maskConfig(reflect.ValueOf(cfg)).(map[string]interface{})
+
+	assert.Equal(t, "app", masked["name"])
+	assert.Equal(t, maskedValue, masked["dsn"])
+	assert.Equal(t, "5s", masked["timeout"])
+	assert.Equal(t, []interface{}{"https://example.com"}, masked["origins"])
+	assert.Equal(t, int32(8080), masked["listener"].(map[string]interface{})["http_port"])
+
+	secrets := maskConfig(reflect.ValueOf(struct {
+		APIToken string
+		Login    string `secret:"true"`
+		Empty    string `secret:"true"`
+		Headers  map[string]string
+	}{
+		APIToken: "token",
+		Login:    "user",
+		Headers:  map[string]string{"X-Api-Key": "key", "Accept": "json"},
+	})).(map[string]interface{})
+	assert.Equal(t, maskedValue, secrets["api_token"])
+	assert.Equal(t, maskedValue, secrets["login"])
+	assert.Equal(t, "", secrets["empty"])
+	assert.Equal(t, map[string]interface{}{"X-Api-Key": maskedValue, "Accept": "json"}, secrets["headers"])
+}
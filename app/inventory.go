@@ -0,0 +1,188 @@
+package app
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi"
+	logger "github.com/sanches1984/gopkg-logger"
+	"github.com/utrack/clay/v2/transport"
+	"google.golang.org/grpc"
+)
+
+// Sources of public routes in inventory
+const (
+	RouteSourceGenerated = "generated"
+	RouteSourceCustom    = "custom"
+	RouteSourceApp       = "app"
+)
+
+// Inventory lists what App registered: public routes, grpc methods and HTTP to RPC bindings
+type Inventory struct {
+	Routes   []RouteInfo   `json:"routes"`
+	RPCs     []RPCInfo     `json:"rpcs"`
+	Bindings []BindingInfo `json:"bindings"`
+}
+
+type RouteInfo struct {
+	Method      string `json:"method"`
+	Pattern     string `json:"pattern"`
+	Source      string `json:"source"`
+	Middlewares int    `json:"middlewares"`
+}
+
+type RPCInfo struct {
+	Method       string `json:"method"`
+	ClientStream bool   `json:"client_stream"`
+	ServerStream bool   `json:"server_stream"`
+}
+
+type BindingInfo struct {
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+	RPC     string `json:"rpc"`
+}
+
+// recordingRouter remembers routes registered by generated code, it stays chi.Router
+// because generated code binds path params only on chi router
+type recordingRouter struct {
+	chi.Router
+	routes map[string]struct{}
+}
+
+func (r *recordingRouter) Method(method, pattern string, h http.Handler) {
+	r.routes[routeKey(method, pattern)] = struct{}{}
+	r.Router.Method(method, pattern, h)
+}
+
+func routeKey(method, pattern string) string {
+	return strings.ToUpper(method) + " " + pattern
+}
+
+// buildInventory is called after registration of public routes
+func (a *App) buildInventory(impl *transport.CompoundServiceDesc, generated map[string]struct{}) {
+	inv := Inventory{Routes: []RouteInfo{}, RPCs: []RPCInfo{}, Bindings: []BindingInfo{}}
+	if impl == nil {
+		a.inventory = inv
+		return
+	}
+
+	if a.httpListener != nil {
+		inv.Routes = a.publicRoutes(generated)
+	}
+
+	// services are registered on separate server, so methods are listed without grpc listener too
+	server := grpc.NewServer()
+	impl.RegisterGRPC(server)
+	for service, info := range server.GetServiceInfo() {
+		for _, m := range info.Methods {
+			inv.RPCs = append(inv.RPCs, RPCInfo{
+				Method:       "/" + service + "/" + m.Name,
+				ClientStream: m.IsClientStream,
+				ServerStream: m.IsServerStream,
+			})
+		}
+	}
+	sort.Slice(inv.RPCs, func(i, j int) bool { return inv.RPCs[i].Method < inv.RPCs[j].Method })
+
+	inv.Bindings = swaggerBindings(impl.SwaggerDef())
+	a.inventory = inv
+}
+
+// publicRoutes walks public mux, middleware count of custom handler includes its own middleware
+func (a *App) publicRoutes(generated map[string]struct{}) []RouteInfo {
+	custom := make(map[string]int)
+	for _, h := range a.customPublicHandler {
+		custom[routeKey(h.Method, h.Pattern)] = len(h.Middleware)
+	}
+	ret := []RouteInfo{}
+	err := chi.Walk(a.httpServer, func(method, route string, _ http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		key := routeKey(method, route)
+		info := RouteInfo{Method: method, Pattern: route, Source: RouteSourceApp, Middlewares: len(middlewares)}
+		if count, ok := custom[key]; ok {
+			info.Source = RouteSourceCustom
+			info.Middlewares += count
+		} else if _, ok := generated[key]; ok {
+			info.Source = RouteSourceGenerated
+		}
+		ret = append(ret, info)
+		return nil
+	})
+	if err != nil {
+		logger.Error(logger.App, "Can't walk public routes: %v", err)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Pattern != ret[j].Pattern {
+			return ret[i].Pattern < ret[j].Pattern
+		}
+		return ret[i].Method < ret[j].Method
+	})
+	return ret
+}
+
+// swaggerBindings reads HTTP to RPC mapping from swagger: operationId is RPC name, the first tag is service
+func swaggerBindings(def []byte) []BindingInfo {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	var op struct {
+		OperationID string   `json:"operationId"`
+		Tags        []string `json:"tags"`
+	}
+	ret := []BindingInfo{}
+	if err := json.Unmarshal(def, &spec); err != nil {
+		logger.Error(logger.App, "Can't read bindings from swagger: %v", err)
+		return ret
+	}
+	for path, operations := range spec.Paths {
+		for method, raw := range operations {
+			// path item has parameters besides operations
+			op.OperationID, op.Tags = "", nil
+			if json.Unmarshal(raw, &op) != nil || op.OperationID == "" {
+				continue
+			}
+			rpc := op.OperationID
+			if len(op.Tags) > 0 {
+				rpc = op.Tags[0] + "/" + rpc
+			}
+			ret = append(ret, BindingInfo{Method: strings.ToUpper(method), Pattern: path, RPC: rpc})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Pattern != ret[j].Pattern {
+			return ret[i].Pattern < ret[j].Pattern
+		}
+		return ret[i].Method < ret[j].Method
+	})
+	return ret
+}
+
+var inventoryTemplate = template.Must(template.New("inventory").Parse(`<h1>Routes</h1>
+<table border="1" cellpadding="4"><tr><th>Method</th><th>Pattern</th><th>Source</th><th>Middlewares</th></tr>
+{{range .Routes}}<tr><td>{{.Method}}</td><td>{{.Pattern}}</td><td>{{.Source}}</td><td>{{.Middlewares}}</td></tr>
+{{end}}</table>
+<h1>RPC</h1>
+<table border="1" cellpadding="4"><tr><th>Method</th><th>Client stream</th><th>Server stream</th></tr>
+{{range .RPCs}}<tr><td>{{.Method}}</td><td>{{.ClientStream}}</td><td>{{.ServerStream}}</td></tr>
+{{end}}</table>
+<h1>HTTP to RPC bindings</h1>
+<table border="1" cellpadding="4"><tr><th>Method</th><th>Pattern</th><th>RPC</th></tr>
+{{range .Bindings}}<tr><td>{{.Method}}</td><td>{{.Pattern}}</td><td>{{.RPC}}</td></tr>
+{{end}}</table>
+`))
+
+// inventoryHandler renders HTML page, JSON with ?format=json
+func (a *App) inventoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(a.inventory)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	if err := inventoryTemplate.Execute(w, a.inventory); err != nil {
+		logger.Error(logger.App, "Can't render inventory: %v", err)
+	}
+}
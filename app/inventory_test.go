@@ -0,0 +1,66 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/utrack/clay/v2/transport"
+)
+
+func TestInventory(t *testing.T) {
+	t.Run("Routes", func(t *testing.T) {
+		ok := func(w http.ResponseWriter, r *http.Request) {}
+		noop := func(next http.Handler) http.Handler { return next }
+		a := &App{httpServer: chi.NewMux()}
+		a.customPublicHandler = []PublicHandler{{Method: http.MethodPost, Pattern: "/custom", HandlerFunc: ok, Middleware: []func(http.Handler) http.Handler{noop, noop}}}
+		a.httpServer.Use(noop)
+
+		generated := make(map[string]struct{})
+		var router transport.Router = &recordingRouter{Router: a.httpServer, routes: generated}
+		chiRouter, isChi := router.(chi.Router)
+		assert.True(t, isChi)
+		chiRouter.Method(http.MethodGet, "/v1/items/{id}", http.HandlerFunc(ok))
+		a.httpServer.Get("/", ok)
+		a.httpServer.MethodFunc(http.MethodPost, "/custom", a.customPublicHandler[0].NewHandlerFuncWithMiddleware())
+
+		assert.Equal(t, []RouteInfo{
+			{Method: http.MethodGet, Pattern: "/", Source: RouteSourceApp, Middlewares: 1},
+			{Method: http.MethodPost, Pattern: "/custom", Source: RouteSourceCustom, Middlewares: 3},
+			{Method: http.MethodGet, Pattern: "/v1/items/{id}", Source: RouteSourceGenerated, Middlewares: 1},
+		}, a.publicRoutes(generated))
+	})
+
+	t.Run("Bindings", func(t *testing.T) {
+		def := `{"paths": {"/v1/items/{id}": {
+			"parameters": [{"name": "id"}],
+			"get": {"operationId": "GetItem", "tags": ["Items"]},
+			"delete": {"operationId": "DeleteItem", "tags": ["Items"]}
+		}}}`
+		assert.Equal(t, []BindingInfo{
+			{Method: http.MethodDelete, Pattern: "/v1/items/{id}", RPC: "Items/DeleteItem"},
+			{Method: http.MethodGet, Pattern: "/v1/items/{id}", RPC: "Items/GetItem"},
+		}, swaggerBindings([]byte(def)))
+		assert.Empty(t, swaggerBindings([]byte("garbage")))
+	})
+
+	t.Run("Handler", func(t *testing.T) {
+		a := &App{inventory: Inventory{
+			Routes:   []RouteInfo{{Method: http.MethodGet, Pattern: "/", Source: RouteSourceApp}},
+			RPCs:     []RPCInfo{{Method: "/items.Items/GetItem"}},
+			Bindings: []BindingInfo{},
+		}}
+		w := httptest.NewRecorder()
+		a.inventoryHandler(w, httptest.NewRequest(http.MethodGet, "/routes?format=json", nil))
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.True(t, strings.Contains(w.Body.String(), `"method":"/items.Items/GetItem"`))
+
+		w = httptest.NewRecorder()
+		a.inventoryHandler(w, httptest.NewRequest(http.MethodGet, "/routes", nil))
+		assert.Equal(t, "text/html", w.Header().Get("Content-Type"))
+		assert.True(t, strings.Contains(w.Body.String(), "<td>/items.Items/GetItem</td>"))
+	})
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sanches1984/gopkg-app/grpcweb"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+// grpcMux serves grpc and public HTTP on one listener when GrpcPort equals HttpPort.
+// grpc requests are served by grpc.Server.ServeHTTP, so keepalive and connection age
+// settings of GRPCServerConfig are not applied, HTTPServerConfig of public server is used instead.
+type grpcMux struct {
+	grpc   *grpc.Server
+	http   http.Handler
+	active int64
+}
+
+func newGRPCMux(grpcServer *grpc.Server, httpHandler http.Handler) *grpcMux {
+	return &grpcMux{grpc: grpcServer, http: httpHandler}
+}
+
+func (m *grpcMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !isGRPCRequest(r) {
+		m.http.ServeHTTP(w, r)
+		return
+	}
+	atomic.AddInt64(&m.active, 1)
+	defer atomic.AddInt64(&m.active, -1)
+	m.grpc.ServeHTTP(w, r)
+}
+
+// drain waits finish of active grpc calls, http.Server.Shutdown doesn't track h2c connections
+func (m *grpcMux) drain(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&m.active) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// isGRPCRequest matches native grpc: HTTP/2 with application/grpc or application/grpc+<codec> content type
+func isGRPCRequest(r *http.Request) bool {
+	if r.ProtoMajor != 2 {
+		return false
+	}
+	contentType := r.Header.Get("Content-Type")
+	return contentType == "application/grpc" ||
+		strings.HasPrefix(contentType, "application/grpc+") ||
+		strings.HasPrefix(contentType, "application/grpc;")
+}
+
+// publicHandler returns handler of public HTTP server, in single port mode it also serves grpc and h2c.
+// gRPC-Web is served before public middleware: they don't support streaming and reject grpc-web CORS headers
+func (a *App) publicHandler() (http.Handler, *grpcMux) {
+	var handler http.Handler = a.httpServer
+	if a.grpcWeb != nil {
+		handler = grpcweb.New(a.grpcServer, handler, a.grpcWeb...)
+	}
+	if !a.grpcOnHTTPPort {
+		return handler, nil
+	}
+	mux := newGRPCMux(a.grpcServer, handler)
+	if _, ok := a.tlsReloader[ServerPublicHTTP]; ok {
+		// HTTP/2 is negotiated with ALPN
+		return mux, mux
+	}
+	return h2c.NewHandler(mux, &http2.Server{IdleTimeout: a.httpServerConfig(ServerPublicHTTP).IdleTimeout}), mux
+}
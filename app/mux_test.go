@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestGRPCMux(t *testing.T) {
+	a := &App{
+		grpcOnHTTPPort:         true,
+		grpcServer:             grpc.NewServer(),
+		httpServer:             chi.NewMux(),
+		customHTTPServerConfig: make(map[string]HTTPServerConfig),
+	}
+	healthpb.RegisterHealthServer(a.grpcServer, grpchealth.NewServer())
+	a.httpServer.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("pong"))
+	})
+	handler, mux := a.publicHandler()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	t.Run("HTTP", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/ping")
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, "pong", string(body))
+	})
+
+	t.Run("GRPC", func(t *testing.T) {
+		conn, err := grpc.Dial(strings.TrimPrefix(server.URL, "http://"), grpc.WithInsecure())
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer conn.Close()
+		resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if assert.Nil(t, err) {
+			assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+		}
+		assert.Nil(t, mux.drain(context.Background()))
+	})
+
+	t.Run("GRPCWeb", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/grpc.health.v1.Health/Check", nil)
+		r.ProtoMajor = 2
+		r.Header.Set("Content-Type", "application/grpc-web+proto")
+		assert.False(t, isGRPCRequest(r))
+		r.Header.Set("Content-Type", "application/grpc+proto")
+		assert.True(t, isGRPCRequest(r))
+	})
+}
@@ -4,12 +4,17 @@ import (
 	"context"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sanches1984/gopkg-app/client/sentry"
+	"github.com/sanches1984/gopkg-app/closer"
+	"github.com/sanches1984/gopkg-app/grpcweb"
+	"github.com/sanches1984/gopkg-app/health"
+	"github.com/sanches1984/gopkg-app/loglevel"
 	"github.com/sanches1984/gopkg-app/metrics"
 	"github.com/sanches1984/gopkg-app/middleware"
 	"github.com/sanches1984/gopkg-app/tracing"
 	errors "github.com/sanches1984/gopkg-errors"
 	"github.com/utrack/clay/v2/transport/swagger"
 	"google.golang.org/grpc"
+	"io"
 	"net/http"
 	"time"
 )
@@ -47,8 +52,7 @@ func (h PublicHandler) NewHandlerFuncWithMiddleware() http.HandlerFunc {
 	}
 }
 
-// Есть проблема, что часть перехватчиков работают на уровне grpc и их надо дублировать при необходимости в ручку
-// TODO нужно сделать обертку, над генерируемым кодом, и вызывать принудительно grpc перехватчики
+// WithPublicHandler registers raw http handler, it doesn't pass grpc interceptors, use WithTypedPublicHandler to run them
 func WithPublicHandler(method, pattern string, handlerFunc http.HandlerFunc, middleware ...func(next http.Handler) http.Handler) OptionFn {
 	return func(a *App) error {
 		a.customPublicHandler = append(a.customPublicHandler, PublicHandler{Method: method, Pattern: pattern, HandlerFunc: handlerFunc, Middleware: middleware})
@@ -56,18 +60,59 @@ func WithPublicHandler(method, pattern string, handlerFunc http.HandlerFunc, mid
 	}
 }
 
-func WithPublicCloser(data PublicCloserFnMap) OptionFn {
+// WithTypedPublicHandler registers handler func(ctx context.Context, req *Req) (*Resp, error) on public HTTP server.
+// Like generated endpoints, request is decoded by transport marshaller and path params (by json name), call passes unary interceptors
+// (validation, error conversion, sentry, recovery) and errors are rendered by gopkg-errors renderer
+func WithTypedPublicHandler(method, pattern string, handler interface{}, middleware ...func(next http.Handler) http.Handler) OptionFn {
 	return func(a *App) error {
+		h, err := newTypedHandler(method, pattern, handler, func() grpc.UnaryServerInterceptor { return a.unaryChain })
+		if err != nil {
+			return err
+		}
+		return WithPublicHandler(method, pattern, h.ServeHTTP, middleware...)(a)
+	}
+}
+
+type publicCloser struct {
+	stage closer.Stage
+	fn    PublicCloserFn
+}
+
+// WithPublicCloser adds closers called on shutdown, by default in closer.DefaultStage
+func WithPublicCloser(data PublicCloserFnMap, stage ...closer.Stage) OptionFn {
+	return func(a *App) error {
+		closerStage := closer.DefaultStage
+		if len(stage) > 0 {
+			closerStage = stage[0]
+		}
 		for name, fn := range data {
-			if _, ok := a.customPublicCloser[name]; ok {
-				return errors.Internal.Err(context.Background(), "Closer already used").WithPayloadKV("name", name)
+			if err := a.addPublicCloser(name, closerStage, fn); err != nil {
+				return err
 			}
-			a.customPublicCloser[name] = fn
 		}
 		return nil
 	}
 }
 
+// WithClientCloser adds client closed on shutdown, in stage declared by closer.Stager or closer.DefaultStage
+func WithClientCloser(name string, client io.Closer) OptionFn {
+	return func(a *App) error {
+		closerStage := closer.DefaultStage
+		if s, ok := client.(closer.Stager); ok {
+			closerStage = s.Stage()
+		}
+		return a.addPublicCloser(name, closerStage, client.Close)
+	}
+}
+
+func (a *App) addPublicCloser(name string, stage closer.Stage, fn PublicCloserFn) error {
+	if _, ok := a.customPublicCloser[name]; ok {
+		return errors.Internal.Err(context.Background(), "Closer already used").WithPayloadKV("name", name)
+	}
+	a.customPublicCloser[name] = publicCloser{stage: stage, fn: fn}
+	return nil
+}
+
 func WithUnaryPrependInterceptor(interceptor ...grpc.UnaryServerInterceptor) OptionFn {
 	return func(a *App) error {
 		a.unaryInterceptor = append(interceptor, a.unaryInterceptor...)
@@ -82,6 +127,20 @@ func WithUnaryAppendInterceptor(interceptor ...grpc.UnaryServerInterceptor) Opti
 	}
 }
 
+func WithStreamPrependInterceptor(interceptor ...grpc.StreamServerInterceptor) OptionFn {
+	return func(a *App) error {
+		a.streamInterceptor = append(interceptor, a.streamInterceptor...)
+		return nil
+	}
+}
+
+func WithStreamAppendInterceptor(interceptor ...grpc.StreamServerInterceptor) OptionFn {
+	return func(a *App) error {
+		a.streamInterceptor = append(a.streamInterceptor, interceptor...)
+		return nil
+	}
+}
+
 func WithPublicMiddleware(middleware ...func(http.Handler) http.Handler) OptionFn {
 	return func(a *App) error {
 		a.publicMiddleware = append(a.publicMiddleware, middleware...)
@@ -89,6 +148,7 @@ func WithPublicMiddleware(middleware ...func(http.Handler) http.Handler) OptionF
 	}
 }
 
+// WithPprof mounts pprof on admin server at /debug/pprof/, it's protected by WithAdminAuth of AdminGroupDebug
 func WithPprof(enabled bool) OptionFn {
 	return func(a *App) error {
 		a.customEnablePprof = enabled
@@ -105,23 +165,67 @@ func WithSwaggerOption(o ...swagger.Option) OptionFn {
 
 func WithMetrics(metric ...prometheus.Collector) OptionFn {
 	return func(a *App) error {
-		metrics.AddCollector(metric...)
+		return a.metrics.Register(metric...)
+	}
+}
+
+// WithMetricsBuckets sets buckets in seconds of HTTP and grpc request duration histograms,
+// by default metrics.DefaultDurationBuckets are used
+func WithMetricsBuckets(buckets ...float64) OptionFn {
+	return func(a *App) error {
+		return a.metrics.SetDurationBuckets(buckets...)
+	}
+}
+
+// WithMetricsPush pushes metrics to Prometheus Pushgateway on interval and on shutdown,
+// it's useful for short-lived processes which exit before scrape
+func WithMetricsPush(url, job string, interval time.Duration, opts ...metrics.PushOption) OptionFn {
+	return func(a *App) error {
+		pusher := metrics.NewPusher(a.metrics, url, job, interval, opts...)
+		pusher.Start()
+		a.publicCloser.AddWithStage("metrics push", closer.StageTelemetry, pusher.Close)
+		return nil
+	}
+}
+
+// WithStatsD sends metrics to StatsD (DogStatsD with metrics.WithDogStatsD) on interval and on shutdown
+func WithStatsD(addr string, interval time.Duration, opts ...metrics.StatsDOption) OptionFn {
+	return func(a *App) error {
+		exporter, err := metrics.NewStatsD(a.metrics, addr, interval, opts...)
+		if err != nil {
+			return err
+		}
+		exporter.Start()
+		a.publicCloser.AddWithStage("statsd", closer.StageTelemetry, exporter.Close)
 		return nil
 	}
 }
 
+// WithTracer sends all traces to jaeger agent by UDP
 func WithTracer(addr string) OptionFn {
+	return WithTracing(tracing.JaegerUDP(addr))
+}
+
+// WithTracing traces public HTTP requests and grpc calls and sends traces with exporter, e.g. tracing.OTLPGRPC
+// or tracing.OTLPHTTP, sampler and propagation formats are set by opts
+func WithTracing(exporter tracing.Exporter, opts ...tracing.Option) OptionFn {
 	return func(a *App) error {
-		tracerCloser, err := tracing.InitTracer(a.config.Name, addr)
+		tracerCloser, err := tracing.Init(a.config.Name, exporter, opts...)
 		if err != nil {
 			return err
 		}
-		a.publicCloser.Add("tracing", func() error {
+		a.publicCloser.AddWithStage("tracing", closer.StageTelemetry, func() error {
 			return tracerCloser.Close()
 		})
+		a.publicMiddleware = append(a.publicMiddleware,
+			middleware.NewTracingMiddleware(tracing.GetTracer()),
+		)
 		a.unaryInterceptor = append(a.unaryInterceptor,
 			middleware.NewUnaryTracingInterceptor(tracing.GetTracer()),
 		)
+		a.streamInterceptor = append(a.streamInterceptor,
+			middleware.NewStreamTracingInterceptor(tracing.GetTracer()),
+		)
 		return nil
 	}
 }
@@ -135,6 +239,130 @@ func WithSentry(project, token string, timeout time.Duration) OptionFn {
 	}
 }
 
+// WithGracefulShutdown sets delay before stop of servers and timeout of draining active requests,
+// timeout should be positive
+func WithGracefulShutdown(delay, timeout time.Duration) OptionFn {
+	return func(a *App) error {
+		g, err := newGraceful(delay, timeout)
+		if err != nil {
+			return err
+		}
+		a.defaultGraceful = g
+		return nil
+	}
+}
+
+// WithServerGracefulShutdown overrides graceful settings of one server: ServerGRPC, ServerPublicHTTP or ServerAdminHTTP
+func WithServerGracefulShutdown(server string, delay, timeout time.Duration) OptionFn {
+	return func(a *App) error {
+		switch server {
+		case ServerGRPC, ServerPublicHTTP, ServerAdminHTTP:
+		default:
+			return errors.Internal.Err(context.Background(), "Unknown server").WithPayloadKV("server", server)
+		}
+		g, err := newGraceful(delay, timeout)
+		if err != nil {
+			return err
+		}
+		a.serverGraceful[server] = g
+		return nil
+	}
+}
+
+// WithWorker runs background worker after servers are up, its context is cancelled on shutdown
+// and worker is waited within graceful timeout. By default failed worker is restarted with backoff
+func WithWorker(name string, runner Runner, opts ...WorkerOption) OptionFn {
+	return func(a *App) error {
+		for _, w := range a.workers {
+			if w.name == name {
+				return errors.Internal.Err(context.Background(), "Worker already registered").WithPayloadKV("worker", name)
+			}
+		}
+		w := &worker{
+			name:       name,
+			runner:     runner,
+			policy:     WorkerRestart,
+			minBackoff: DefaultWorkerMinBackoff,
+			maxBackoff: DefaultWorkerMaxBackoff,
+			panics:     a.metrics.CountPanic,
+		}
+		for _, opt := range opts {
+			opt(w)
+		}
+		a.workers = append(a.workers, w)
+		return nil
+	}
+}
+
+// WithGRPCServerConfig sets message size limits, keepalive enforcement and connection age of grpc server
+func WithGRPCServerConfig(cfg GRPCServerConfig) OptionFn {
+	return func(a *App) error {
+		a.grpcServerConfig = cfg
+		return nil
+	}
+}
+
+// WithGRPCServerOption adds options to grpc.NewServer, they are applied after options of App
+func WithGRPCServerOption(opts ...grpc.ServerOption) OptionFn {
+	return func(a *App) error {
+		a.grpcServerOption = append(a.grpcServerOption, opts...)
+		return nil
+	}
+}
+
+// WithGRPCTLS serves grpc with TLS, set ClientCAFile to require client certificates.
+// Certificates are shared and reloaded together with HTTP listeners using the same files
+func WithGRPCTLS(cfg TLSConfig) OptionFn {
+	return func(a *App) error {
+		return a.setTLS(ServerGRPC, cfg)
+	}
+}
+
+// WithGRPCWeb serves grpc services to browser clients with gRPC-Web on public HTTP listener,
+// calls pass the same interceptors as native grpc. Without origins only same-origin calls are allowed
+func WithGRPCWeb(allowedOrigins ...string) OptionFn {
+	return func(a *App) error {
+		if a.httpListener == nil {
+			return errors.Internal.Err(context.Background(), "gRPC-Web requires public HTTP listener")
+		}
+		a.grpcWeb = []grpcweb.Option{}
+		if len(allowedOrigins) > 0 {
+			a.grpcWeb = append(a.grpcWeb, grpcweb.WithAllowedOrigins(allowedOrigins...))
+		}
+		return nil
+	}
+}
+
+// WithPublicHTTPServerConfig sets timeouts and header limits of public HTTP server
+func WithPublicHTTPServerConfig(cfg HTTPServerConfig) OptionFn {
+	return func(a *App) error {
+		a.customHTTPServerConfig[ServerPublicHTTP] = cfg
+		return nil
+	}
+}
+
+// WithAdminHTTPServerConfig sets timeouts and header limits of admin HTTP server
+func WithAdminHTTPServerConfig(cfg HTTPServerConfig) OptionFn {
+	return func(a *App) error {
+		a.customHTTPServerConfig[ServerAdminHTTP] = cfg
+		return nil
+	}
+}
+
+// WithPublicTLS serves public HTTP listener with TLS
+func WithPublicTLS(cfg TLSConfig) OptionFn {
+	return func(a *App) error {
+		return a.setTLS(ServerPublicHTTP, cfg)
+	}
+}
+
+// WithAdminTLS serves admin HTTP listener with TLS, set ClientCAFile to require client certificates
+func WithAdminTLS(cfg TLSConfig) OptionFn {
+	return func(a *App) error {
+		return a.setTLS(ServerAdminHTTP, cfg)
+	}
+}
+
 func WithFavicon(favicon []byte) OptionFn {
 	return func(a *App) error {
 		a.favicon = favicon
@@ -148,3 +376,75 @@ func WithAdminURLPrefix(prefix string) OptionFn {
 		return nil
 	}
 }
+
+// WithAdminAuth protects admin route groups: AdminGroupDocs, AdminGroupMetrics, AdminGroupHealth, AdminGroupDebug.
+// Without groups all routes are protected, later call overrides auth of the same group
+func WithAdminAuth(auth AdminAuth, groups ...string) OptionFn {
+	return func(a *App) error {
+		if len(groups) == 0 {
+			groups = adminGroups
+		}
+		for _, group := range groups {
+			if !isAdminGroup(group) {
+				return errors.Internal.Err(context.Background(), "Unknown admin route group").WithPayloadKV("group", group)
+			}
+		}
+		compiled, err := newAdminAuth(auth)
+		if err != nil {
+			return err
+		}
+		for _, group := range groups {
+			a.adminAuth[group] = compiled
+		}
+		return nil
+	}
+}
+
+// WithInfoConfig shows cfg as effective config on /info instead of App Config, secret fields are masked:
+// fields with tag secret:"true" and names containing password, secret, token, key, dsn or credential
+func WithInfoConfig(cfg interface{}) OptionFn {
+	return func(a *App) error {
+		a.infoConfig = cfg
+		return nil
+	}
+}
+
+// WithConfigWatcher starts reloading of config on SIGHUP and file change, watcher is stopped on shutdown.
+// Current config of watcher is shown on /info unless WithInfoConfig is set
+func WithConfigWatcher(w *ConfigWatcher) OptionFn {
+	return func(a *App) error {
+		// counter is set before start, so reloads rejected right after start are counted
+		w.setErrorCounter(a.metrics.CountConfigReloadError)
+		if err := w.Start(); err != nil {
+			return err
+		}
+		a.configWatcher = w
+		a.publicCloser.AddWithStage("config watcher", closer.StageClients, w.Close)
+		return nil
+	}
+}
+
+// WithLoggerLevelSetter makes admin endpoint /log/level change level of gopkg-logger with setter,
+// including revert after ttl
+func WithLoggerLevelSetter(setter loglevel.LoggerSetter) OptionFn {
+	return func(a *App) error {
+		loglevel.SetLoggerSetter(setter)
+		return nil
+	}
+}
+
+// WithDebugTokenSecret enables forcing of debug logs per request with header loglevel.DebugHeader
+// (grpc metadata loglevel.DebugMetadata), tokens are created by loglevel.SignDebugToken with the same secret
+func WithDebugTokenSecret(secret string) OptionFn {
+	return func(a *App) error {
+		loglevel.SetDebugSecret(secret)
+		return nil
+	}
+}
+
+// WithHealthCheck registers dependency check for /health/ready (and /health/live with health.WithLiveness) on admin server
+func WithHealthCheck(name string, check health.CheckFn, opts ...health.CheckOption) OptionFn {
+	return func(a *App) error {
+		return a.health.Add(name, check, opts...)
+	}
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/sanches1984/gopkg-app/closer"
+	"github.com/stretchr/testify/assert"
+)
+
+type stagedClient struct {
+	stage  closer.Stage
+	closed bool
+}
+
+func (c *stagedClient) Close() error {
+	c.closed = true
+	return nil
+}
+
+func (c *stagedClient) Stage() closer.Stage {
+	return c.stage
+}
+
+type plainClient struct{}
+
+func (plainClient) Close() error {
+	return nil
+}
+
+func TestPublicCloser(t *testing.T) {
+	t.Run("Stage", func(t *testing.T) {
+		a := &App{customPublicCloser: make(map[string]publicCloser)}
+		assert.Nil(t, WithPublicCloser(PublicCloserFnMap{"db": func() error { return nil }})(a))
+		assert.Nil(t, WithPublicCloser(PublicCloserFnMap{"tracer": func() error { return nil }}, closer.StageTelemetry)(a))
+		assert.Equal(t, closer.DefaultStage, a.customPublicCloser["db"].stage)
+		assert.Equal(t, closer.StageTelemetry, a.customPublicCloser["tracer"].stage)
+	})
+
+	t.Run("Client stage", func(t *testing.T) {
+		a := &App{customPublicCloser: make(map[string]publicCloser)}
+		client := &stagedClient{stage: closer.StageWorkers}
+		assert.Nil(t, WithClientCloser("email", client)(a))
+		assert.Nil(t, WithClientCloser("sms", plainClient{})(a))
+		assert.Equal(t, closer.StageWorkers, a.customPublicCloser["email"].stage)
+		assert.Equal(t, closer.DefaultStage, a.customPublicCloser["sms"].stage)
+		assert.Nil(t, a.customPublicCloser["email"].fn())
+		assert.True(t, client.closed)
+	})
+
+	t.Run("Already used", func(t *testing.T) {
+		a := &App{customPublicCloser: make(map[string]publicCloser)}
+		assert.Nil(t, WithPublicCloser(PublicCloserFnMap{"email": func() error { return nil }})(a))
+		assert.NotNil(t, WithClientCloser("email", plainClient{})(a))
+	})
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/sanches1984/gopkg-app/types"
+	errors "github.com/sanches1984/gopkg-errors"
+	"github.com/utrack/clay/v2/transport/httpruntime"
+	"google.golang.org/grpc"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// typedHandler calls func(ctx context.Context, req *Req) (*Resp, error) like generated clay handlers:
+// request is decoded by transport marshaller and filled from path params, call passes unary interceptors of App,
+// errors are rendered by httpruntime.SetError
+type typedHandler struct {
+	info    *grpc.UnaryServerInfo
+	fn      reflect.Value
+	reqType reflect.Type
+	chain   func() grpc.UnaryServerInterceptor
+}
+
+func newTypedHandler(method, pattern string, fn interface{}, chain func() grpc.UnaryServerInterceptor) (*typedHandler, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.Type().NumIn() != 2 || v.Type().NumOut() != 2 ||
+		v.Type().In(0) != contextType || v.Type().In(1).Kind() != reflect.Ptr ||
+		v.Type().Out(0).Kind() != reflect.Ptr || v.Type().Out(1) != errorType {
+		return nil, errors.Internal.Err(context.Background(), "Handler must be func(context.Context, *Req) (*Resp, error)").
+			WithPayloadKV("pattern", pattern, "type", fmt.Sprintf("%T", fn))
+	}
+	return &typedHandler{
+		info:    &grpc.UnaryServerInfo{FullMethod: typedMethodName(method, pattern)},
+		fn:      v,
+		reqType: v.Type().In(1).Elem(),
+		chain:   chain,
+	}, nil
+}
+
+func (h *typedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	inbound, outbound := httpruntime.MarshalerForRequest(r)
+	req := reflect.New(h.reqType).Interface()
+	// empty body is allowed, e.g. for GET requests
+	if err := inbound.Unmarshal(r.Body, req); err != nil && err != io.EOF {
+		httpruntime.SetError(r.Context(), r, w, httpruntime.TransformUnmarshalerError(err))
+		return
+	}
+	if err := setPathParams(r, reflect.ValueOf(req).Elem()); err != nil {
+		httpruntime.SetError(r.Context(), r, w, err)
+		return
+	}
+
+	resp, err := h.invoke(r.Context(), req)
+	if err != nil {
+		httpruntime.SetError(r.Context(), r, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", outbound.ContentType())
+	if err := outbound.Marshal(w, resp); err != nil {
+		httpruntime.SetError(r.Context(), r, w, errors.Internal.ErrWrap(r.Context(), "Can't marshal response", err))
+	}
+}
+
+func (h *typedHandler) invoke(ctx context.Context, req interface{}) (interface{}, error) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		out := h.fn.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(req)})
+		if err, _ := out[1].Interface().(error); err != nil {
+			return nil, err
+		}
+		return out[0].Interface(), nil
+	}
+	if chain := h.chain(); chain != nil {
+		return chain(ctx, req, h.info, handler)
+	}
+	return handler(ctx, req)
+}
+
+// typedMethodName makes grpc-like FullMethod for interceptors, so metrics and logs split it
+// into service "http" and method, e.g. "POST /v1/items/{id}" gives "/http/POST_v1_items_{id}"
+func typedMethodName(method, pattern string) string {
+	return "/http/" + method + "_" + strings.Replace(strings.Trim(pattern, "/"), "/", "_", -1)
+}
+
+// setPathParams fills request fields from path params by json name, path params override body like in grpc-gateway
+func setPathParams(r *http.Request, req reflect.Value) error {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || req.Kind() != reflect.Struct {
+		return nil
+	}
+	for i, key := range rctx.URLParams.Keys {
+		field, ok := fieldByJSONName(req, key)
+		if !ok {
+			continue
+		}
+		if err := setConfigValue(field, rctx.URLParams.Values[i]); err != nil {
+			return errors.BadRequest.ErrWrap(r.Context(), "Invalid path parameter", err).WithPayloadKV("param", key)
+		}
+	}
+	return nil
+}
+
+func fieldByJSONName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		fieldName := types.CamelToSnakeCase(sf.Name)
+		if tagName := strings.Split(sf.Tag.Get("json"), ",")[0]; tagName != "" {
+			fieldName = tagName
+		}
+		if fieldName == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	pkgtransport "github.com/sanches1984/gopkg-app/transport"
+	pkgvalidator "github.com/sanches1984/gopkg-app/validator"
+	validatormw "github.com/sanches1984/gopkg-app/validator/middleware"
+	errmw "github.com/sanches1984/gopkg-errors/middleware"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+type testItemRequest struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name" validate:"required"`
+}
+
+type testItemResponse struct {
+	Name string `json:"name"`
+}
+
+func TestTypedHandler(t *testing.T) {
+	getItem := func(ctx context.Context, req *testItemRequest) (*testItemResponse, error) {
+		if req.ID == 0 {
+			return nil, context.Canceled
+		}
+		return &testItemResponse{Name: fmt.Sprintf("%d:%s", req.ID, req.Name)}, nil
+	}
+
+	t.Run("Signature", func(t *testing.T) {
+		noChain := func() grpc.UnaryServerInterceptor { return nil }
+		for _, fn := range []interface{}{
+			nil,
+			"handler",
+			func(req *testItemRequest) (*testItemResponse, error) { return nil, nil },
+			func(ctx context.Context, req testItemRequest) (*testItemResponse, error) { return nil, nil },
+			func(ctx context.Context, req *testItemRequest) *testItemResponse { return nil },
+		} {
+			_, err := newTypedHandler("GET", "/items", fn, noChain)
+			assert.NotNil(t, err)
+		}
+	})
+
+	t.Run("Interceptors", func(t *testing.T) {
+		var fullMethod string
+		chain := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			fullMethod = info.FullMethod
+			return handler(ctx, req)
+		}
+		h, err := newTypedHandler("POST", "/v1/items/{id}", getItem, func() grpc.UnaryServerInterceptor { return chain })
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		resp, err := h.invoke(context.Background(), &testItemRequest{ID: 1, Name: "a"})
+		assert.Nil(t, err)
+		assert.Equal(t, &testItemResponse{Name: "1:a"}, resp)
+		assert.Equal(t, "/http/POST_v1_items_{id}", fullMethod)
+
+		resp, err = h.invoke(context.Background(), &testItemRequest{})
+		assert.Equal(t, context.Canceled, err)
+		assert.Nil(t, resp)
+	})
+}
+
+func TestTypedHandlerHTTP(t *testing.T) {
+	pkgtransport.Override(nil)
+	errorCounter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_errors"})
+	chain := grpc_middleware.ChainUnaryServer(
+		errmw.NewConvertErrorsServerInterceptor(getErrorConverters("test"), &errorCounter),
+		validatormw.NewValidateServerInterceptor(pkgvalidator.New()),
+	)
+
+	var calls int
+	updateItem := func(ctx context.Context, req *testItemRequest) (*testItemResponse, error) {
+		calls++
+		if req.Name == "fail" {
+			return nil, fmt.Errorf("can't update item %d", req.ID)
+		}
+		return &testItemResponse{Name: fmt.Sprintf("%d:%s", req.ID, req.Name)}, nil
+	}
+	h, err := newTypedHandler(http.MethodPut, "/items/{id}", updateItem, func() grpc.UnaryServerInterceptor { return chain })
+	if !assert.Nil(t, err) {
+		return
+	}
+	router := chi.NewRouter()
+	router.Method(http.MethodPut, "/items/{id}", h)
+
+	serve := func(path, body string) *httptest.ResponseRecorder {
+		calls = 0
+		r := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("BodyAndPath", func(t *testing.T) {
+		w := serve("/items/7", `{"id": 1, "name": "box"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		var resp testItemResponse
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "7:box", resp.Name)
+	})
+
+	t.Run("MalformedBody", func(t *testing.T) {
+		w := serve("/items/7", `{"name": `)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("InvalidPathParam", func(t *testing.T) {
+		w := serve("/items/seven", `{"name": "box"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("ValidationError", func(t *testing.T) {
+		w := serve("/items/7", `{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("HandlerError", func(t *testing.T) {
+		w := serve("/items/7", `{"name": "fail"}`)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, 1, calls)
+	})
+}
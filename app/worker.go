@@ -0,0 +1,159 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sanches1984/gopkg-app/client/sentry"
+	"github.com/sanches1984/gopkg-app/closer"
+	errors "github.com/sanches1984/gopkg-errors"
+	logger "github.com/sanches1984/gopkg-logger"
+)
+
+const (
+	DefaultWorkerMinBackoff = time.Second
+	DefaultWorkerMaxBackoff = time.Minute
+)
+
+// Runner is background worker: consumer, poller or job pool. Run should return when ctx is cancelled
+type Runner interface {
+	Run(ctx context.Context) error
+}
+
+// RunnerFunc allows to use function as Runner
+type RunnerFunc func(ctx context.Context) error
+
+func (f RunnerFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+// WorkerPolicy defines reaction on error or panic of worker, nil returned before shutdown just finishes worker
+type WorkerPolicy int
+
+const (
+	// WorkerRestart restarts worker with exponential backoff
+	WorkerRestart WorkerPolicy = iota
+	// WorkerFailApp shuts down whole App, Run exits with code 1
+	WorkerFailApp
+)
+
+type WorkerOption func(w *worker)
+
+// WithWorkerPolicy sets reaction on worker failure, WorkerRestart by default
+func WithWorkerPolicy(policy WorkerPolicy) WorkerOption {
+	return func(w *worker) {
+		w.policy = policy
+	}
+}
+
+// WithWorkerBackoff sets delays between restarts, delay is doubled after every failure up to max
+// and is reset when worker worked longer than max
+func WithWorkerBackoff(min, max time.Duration) WorkerOption {
+	return func(w *worker) {
+		w.minBackoff = min
+		w.maxBackoff = max
+	}
+}
+
+type worker struct {
+	name       string
+	runner     Runner
+	policy     WorkerPolicy
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	panics     prometheus.Counter
+}
+
+// runWorkers starts workers, they are stopped at closer.StageWorkers within graceful timeout
+func (a *App) runWorkers() {
+	for _, w := range a.workers {
+		w := w
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func(w *worker) {
+			defer close(done)
+			a.runWorker(ctx, w)
+		}(w)
+
+		name := "worker:" + w.name
+		a.publicCloser.AddContext(name, closer.StageWorkers, a.defaultGraceful.Timeout, func(closeCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				logger.Info(logger.App, "%s: stopped", name)
+				return nil
+			case <-closeCtx.Done():
+				return errors.Internal.Err(context.Background(), "Worker is not stopped").
+					WithLogKV("worker", w.name, "error", closeCtx.Err())
+			}
+		})
+	}
+}
+
+func (a *App) runWorker(ctx context.Context, w *worker) {
+	backoff := w.minBackoff
+	for {
+		logger.Info(logger.App, "worker:%s: started", w.name)
+		start := time.Now()
+		err := runWorkerOnce(ctx, w)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			logger.Info(logger.App, "worker:%s: finished", w.name)
+			return
+		}
+
+		logger.Error(logger.App, "worker:%s: failed: %v", w.name, err)
+		sentry.Error(err, "worker", w.name)
+		if w.policy == WorkerFailApp {
+			a.fail(errors.Internal.ErrWrap(context.Background(), "Worker failed", err).WithLogKV("worker", w.name))
+			return
+		}
+
+		if time.Since(start) > w.maxBackoff {
+			backoff = w.minBackoff
+		}
+		logger.Info(logger.App, "worker:%s: restart in %s", w.name, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		if backoff *= 2; backoff > w.maxBackoff {
+			backoff = w.maxBackoff
+		}
+	}
+}
+
+// runWorkerOnce converts panic of worker to error
+func runWorkerOnce(ctx context.Context, w *worker) (err error) {
+	defer func() {
+		if data := recover(); data != nil {
+			if w.panics != nil {
+				w.panics.Inc()
+			}
+			sentry.Panic(data, "worker", w.name)
+			err = errors.Internal.Err(context.Background(), "Worker panic").WithLogKV("panic", fmt.Sprint(data))
+		}
+	}()
+	return w.runner.Run(ctx)
+}
+
+// fail shuts down App, the first error is returned by runServers
+func (a *App) fail(err error) {
+	a.failureMu.Lock()
+	if a.failure == nil {
+		a.failure = err
+	}
+	a.failureMu.Unlock()
+	go a.publicCloser.CloseAll()
+}
+
+func (a *App) failureErr() error {
+	a.failureMu.Lock()
+	defer a.failureMu.Unlock()
+	return a.failure
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sanches1984/gopkg-app/closer"
+	"github.com/sanches1984/gopkg-app/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestWorkerApp() *App {
+	return &App{
+		metrics:         metrics.NewRegistry("test"),
+		publicCloser:    closer.New(),
+		defaultGraceful: Graceful{Timeout: time.Second},
+	}
+}
+
+func TestWorker(t *testing.T) {
+	t.Run("Restart", func(t *testing.T) {
+		a := newTestWorkerApp()
+		var runs int32
+		runner := RunnerFunc(func(ctx context.Context) error {
+			if atomic.AddInt32(&runs, 1) < 3 {
+				panic("boom")
+			}
+			<-ctx.Done()
+			return nil
+		})
+		assert.Nil(t, WithWorker("consumer", runner, WithWorkerBackoff(time.Millisecond, 10*time.Millisecond))(a))
+		assert.NotNil(t, WithWorker("consumer", runner)(a))
+
+		a.runWorkers()
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&runs) == 3 }, time.Second, time.Millisecond)
+		assert.Equal(t, float64(2), testutil.ToFloat64(a.Metrics().CountPanic))
+		assert.Nil(t, a.publicCloser.CloseAllWithResult())
+		assert.Nil(t, a.failureErr())
+	})
+
+	t.Run("FailApp", func(t *testing.T) {
+		a := newTestWorkerApp()
+		runner := RunnerFunc(func(ctx context.Context) error {
+			return context.Canceled
+		})
+		assert.Nil(t, WithWorker("poller", runner, WithWorkerPolicy(WorkerFailApp))(a))
+
+		a.runWorkers()
+		select {
+		case <-a.publicCloser.Closing():
+			a.publicCloser.Wait()
+			assert.NotNil(t, a.failureErr())
+		case <-time.After(time.Second):
+			t.Error("failed worker should shut down App")
+		}
+	})
+
+	t.Run("NotStopped", func(t *testing.T) {
+		a := newTestWorkerApp()
+		a.defaultGraceful.Timeout = 10 * time.Millisecond
+		release := make(chan struct{})
+		defer close(release)
+		runner := RunnerFunc(func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+		assert.Nil(t, WithWorker("stuck", runner)(a))
+
+		a.runWorkers()
+		err := a.publicCloser.CloseAllWithResult()
+		if assert.IsType(t, &closer.Error{}, err) {
+			assert.Equal(t, "worker:stuck", err.(*closer.Error).Failures[0].Name)
+		}
+	})
+
+	t.Run("WorkerMode", func(t *testing.T) {
+		a := newTestWorkerApp()
+		httpListener, err := net.Listen("tcp", "127.0.0.1:0")
+		if !assert.Nil(t, err) {
+			return
+		}
+		a.httpListener = httpListener
+		a.grpcOnHTTPPort = true
+
+		a.closePublicListeners()
+		assert.Nil(t, a.httpListener)
+		assert.False(t, a.grpcOnHTTPPort)
+		_, err = httpListener.Accept()
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,194 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	errors "github.com/sanches1984/gopkg-errors"
+	logger "github.com/sanches1984/gopkg-logger"
+)
+
+// Reloader keeps certificate and client CA loaded from files and reloads them on file change.
+// Directories are watched, so kubernetes secrets updated with symlink swap are supported too.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	caPool  *x509.CertPool
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewReloader loads certificate, key and optional client CA (caFile may be empty) and starts watching files
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile, done: make(chan struct{})}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Internal.ErrWrap(context.Background(), "certs: can't create watcher", err)
+	}
+	dirs := make(map[string]struct{})
+	for _, file := range []string{certFile, keyFile, caFile} {
+		if file != "" {
+			dirs[filepath.Dir(file)] = struct{}{}
+		}
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return nil, errors.Internal.ErrWrap(context.Background(), "certs: can't watch directory", err).
+				WithLogKV("dir", dir)
+		}
+	}
+	r.watcher = watcher
+	go r.watch()
+	return r, nil
+}
+
+// Key identifies files of reloader, so one reloader can be shared by several listeners
+func Key(certFile, keyFile, caFile string) string {
+	return certFile + "|" + keyFile + "|" + caFile
+}
+
+// Reload reads files, on error previous certificate is kept
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errors.Internal.ErrWrap(context.Background(), "certs: can't load key pair", err).
+			WithLogKV("cert", r.certFile, "key", r.keyFile)
+	}
+
+	var caPool *x509.CertPool
+	if r.caFile != "" {
+		caPool, err = LoadCertPool(r.caFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.caPool = caPool
+	r.mu.Unlock()
+	return nil
+}
+
+// LoadCertPool reads PEM encoded certificates
+func LoadCertPool(caFile string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.Internal.ErrWrap(context.Background(), "certs: can't read CA", err).WithLogKV("ca", caFile)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.Internal.Err(context.Background(), "certs: no certificates in CA file").WithLogKV("ca", caFile)
+	}
+	return pool, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// ClientCAs returns pool of client CA, nil without CA file
+func (r *Reloader) ClientCAs() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.caPool
+}
+
+// ServerConfig returns TLS config for server, client certificates are required when CA file is set.
+// Client certificates are verified with current CA on every handshake, so CA reload doesn't need new config.
+func (r *Reloader) ServerConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+	if r.caFile != "" {
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyPeerCertificate = r.verifyClientCertificate
+	}
+	return cfg
+}
+
+func (r *Reloader) verifyClientCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return errors.Internal.Err(context.Background(), "certs: client certificate required")
+	}
+	chain := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return errors.Internal.ErrWrap(context.Background(), "certs: bad client certificate", err)
+		}
+		chain = append(chain, cert)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         r.ClientCAs(),
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err
+}
+
+// Close stops watching files, it is safe to call Close concurrently
+func (r *Reloader) Close() error {
+	var err error
+	r.once.Do(func() {
+		close(r.done)
+		err = r.watcher.Close()
+	})
+	return err
+}
+
+func (r *Reloader) watch() {
+	files := map[string]struct{}{}
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file != "" {
+			files[filepath.Clean(file)] = struct{}{}
+		}
+	}
+	for {
+		select {
+		case <-r.done:
+			return
+		case event, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			// own files and kubernetes secret "..data" symlink swap trigger reload
+			if _, own := files[filepath.Clean(event.Name)]; !own && filepath.Base(event.Name) != "..data" {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				logger.Error(logger.App, "certs: reload failed: %v", err)
+				continue
+			}
+			logger.Info(logger.App, "certs: reloaded %s", r.certFile)
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Error(logger.App, "certs: watcher error: %v", err)
+		}
+	}
+}
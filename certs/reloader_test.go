@@ -0,0 +1,100 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestCert(t *testing.T, certFile, keyFile, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// key is written first, certificate write triggers reload of matching pair
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func commonName(r *Reloader) string {
+	cert, _ := r.GetCertificate(nil)
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return ""
+	}
+	return parsed.Subject.CommonName
+}
+
+func TestReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	t.Run("MissingFiles", func(t *testing.T) {
+		_, err := NewReloader(certFile, keyFile, "")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		writeTestCert(t, certFile, keyFile, "first")
+		r, err := NewReloader(certFile, keyFile, "")
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer r.Close()
+		assert.Equal(t, "first", commonName(r))
+		assert.Nil(t, r.ServerConfig().VerifyPeerCertificate)
+
+		writeTestCert(t, certFile, keyFile, "second")
+		assert.Eventually(t, func() bool { return commonName(r) == "second" }, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("ConcurrentClose", func(t *testing.T) {
+		r, err := NewReloader(certFile, keyFile, "")
+		if !assert.Nil(t, err) {
+			return
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NotPanics(t, func() { _ = r.Close() })
+			}()
+		}
+		wg.Wait()
+	})
+}
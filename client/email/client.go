@@ -5,6 +5,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sanches1984/gopkg-app/app"
 	"github.com/sanches1984/gopkg-app/client/email/provider"
+	"github.com/sanches1984/gopkg-app/closer"
 )
 
 type Client struct {
@@ -13,6 +14,8 @@ type Client struct {
 	sender        provider.ISender
 	showInfo      bool
 	showError     bool
+	closer        app.PublicCloserFn
+	closerStage   closer.Stage
 }
 
 func NewMessage() *provider.Message {
@@ -20,7 +23,7 @@ func NewMessage() *provider.Message {
 }
 
 func NewClient(provider provider.IProvider, fromAddress, fromName string, option ...Option) (*Client, app.PublicCloserFn, error) {
-	c := Client{}
+	c := Client{closerStage: closer.StageClients}
 	for _, o := range option {
 		o(&c)
 	}
@@ -29,9 +32,23 @@ func NewClient(provider provider.IProvider, fromAddress, fromName string, option
 		return nil, closer, err
 	}
 	c.sender = sender
+	c.closer = closer
 	return &c, closer, nil
 }
 
+// Close closes provider connection, client can be passed to app.WithClientCloser
+func (c *Client) Close() error {
+	if c.closer == nil {
+		return nil
+	}
+	return c.closer()
+}
+
+// Stage returns closer stage set by WithCloserStage, closer.StageClients by default
+func (c *Client) Stage() closer.Stage {
+	return c.closerStage
+}
+
 func (c *Client) Send(ctx context.Context, subject string, addressNameMap map[string]string, msg *provider.Message) error {
 	msg.Subject = subject
 	msg.To = make(provider.ContactList, 0, len(addressNameMap))
@@ -6,6 +6,7 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sanches1984/gopkg-app/app"
+	"github.com/sanches1984/gopkg-app/closer"
 )
 
 type Client struct {
@@ -14,10 +15,12 @@ type Client struct {
 	sender        provider.ISender
 	showInfo      bool
 	showError     bool
+	closer        app.PublicCloserFn
+	closerStage   closer.Stage
 }
 
 func NewClient(provider provider.IProvider, option ...Option) (*Client, app.PublicCloserFn, error) {
-	c := &Client{}
+	c := &Client{closerStage: closer.StageClients}
 	for _, o := range option {
 		o(c)
 	}
@@ -26,9 +29,23 @@ func NewClient(provider provider.IProvider, option ...Option) (*Client, app.Publ
 		return nil, closer, err
 	}
 	c.sender = sender
+	c.closer = closer
 	return c, closer, nil
 }
 
+// Close closes provider connection, client can be passed to app.WithClientCloser
+func (c Client) Close() error {
+	if c.closer == nil {
+		return nil
+	}
+	return c.closer()
+}
+
+// Stage returns closer stage set by WithCloserStage, closer.StageClients by default
+func (c Client) Stage() closer.Stage {
+	return c.closerStage
+}
+
 func (c Client) Send(ctx context.Context, phone int64, message string) error {
 	err := c.sender.Send(ctx, phone, message)
 	if err == nil {
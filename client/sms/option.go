@@ -1,6 +1,9 @@
 package sms
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sanches1984/gopkg-app/closer"
+)
 
 type Option func(c *Client)
 
@@ -34,3 +37,10 @@ func WithErrorLog() Option {
 		c.showError = true
 	}
 }
+
+// WithCloserStage sets shutdown stage returned by Client.Stage, closer.StageClients by default
+func WithCloserStage(stage closer.Stage) Option {
+	return func(c *Client) {
+		c.closerStage = stage
+	}
+}
@@ -1,57 +1,179 @@
 package closer
 
 import (
-	"fmt"
+	"context"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	logger "github.com/sanches1984/gopkg-logger"
+)
+
+// Stage defines order of closing, stages are closed one by one from lower to higher value,
+// functions inside one stage are closed concurrently
+type Stage int
+
+const (
+	// StageServers stops incoming traffic: grpc and http servers
+	StageServers Stage = 100
+	// StageWorkers stops background workers and consumers
+	StageWorkers Stage = 200
+	// StageClients closes connections to databases, queues and other services
+	StageClients Stage = 300
+	// StageTelemetry flushes tracing, metrics and error reporting
+	StageTelemetry Stage = 400
+
+	// DefaultStage is used by Add
+	DefaultStage = StageClients
+)
+
+// Stager is implemented by closers declaring their own stage
+type Stager interface {
+	Stage() Stage
+}
+
+const (
+	// DefaultFuncTimeout limits every close function
+	DefaultFuncTimeout = 20 * time.Second
+	// DefaultTimeout limits whole CloseAll, stages not started before deadline are reported as timed out
+	DefaultTimeout = 60 * time.Second
 )
 
 // GlobalCloser automatically calls when app is terminating
 var globalCloser = New()
 
+// exit is called on forced shutdown
+var exit = os.Exit
+
 // Add adds `func() error` callback to the globalCloser
 func Add(name string, f func() error) {
 	globalCloser.Add(name, f)
 }
 
+// AddWithStage adds `func() error` callback to the globalCloser in stage
+func AddWithStage(name string, stage Stage, f func() error) {
+	globalCloser.AddWithStage(name, stage, f)
+}
+
+// AddContext adds `func(ctx) error` callback to the globalCloser, ctx is cancelled after timeout (DefaultFuncTimeout if 0)
+func AddContext(name string, stage Stage, timeout time.Duration, f func(ctx context.Context) error) {
+	globalCloser.AddContext(name, stage, timeout, f)
+}
+
 func Wait() {
 	globalCloser.Wait()
 }
 
+func WaitContext(ctx context.Context) error {
+	return globalCloser.WaitContext(ctx)
+}
+
 func CloseAll() {
 	globalCloser.CloseAll()
 }
 
+func CloseAllWithResult() error {
+	return globalCloser.CloseAllWithResult()
+}
+
+// Error contains all close functions which failed or timed out
+type Error struct {
+	Failures []Failure
+}
+
+type Failure struct {
+	Name    string
+	Err     error
+	Timeout bool
+}
+
+func (e *Error) Error() string {
+	list := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		if f.Timeout {
+			list = append(list, f.Name+": timeout")
+		} else {
+			list = append(list, f.Name+": "+f.Err.Error())
+		}
+	}
+	return "closer: " + strings.Join(list, "; ")
+}
+
 type Closer struct {
 	sync.Mutex
-	once  sync.Once
-	done  chan struct{}
-	funcs map[string]func() error
+	once         sync.Once
+	closing      chan struct{}
+	done         chan struct{}
+	funcs        map[string]closeFunc
+	pending      map[string]struct{}
+	funcTimeout  time.Duration
+	totalTimeout time.Duration
+	err          error
+}
+
+type closeFunc struct {
+	stage   Stage
+	timeout time.Duration
+	fn      func(ctx context.Context) error
 }
 
 // New returns new Closer, if []os.Signal is specified Closer will automatically call CloseAll when one of signals is received from OS
 func New(sig ...os.Signal) *Closer {
-	c := &Closer{done: make(chan struct{}), funcs: make(map[string]func() error)}
+	c := &Closer{
+		closing:      make(chan struct{}),
+		done:         make(chan struct{}),
+		funcs:        make(map[string]closeFunc),
+		funcTimeout:  DefaultFuncTimeout,
+		totalTimeout: DefaultTimeout,
+	}
 	if len(sig) > 0 {
 		go func() {
-			ch := make(chan os.Signal, 1)
+			ch := make(chan os.Signal, 2)
 			signal.Notify(ch, sig...)
+			defer signal.Stop(ch)
 			<-ch
-			signal.Stop(ch)
-			c.CloseAll()
+			go c.CloseAll()
+			// second signal doesn't wait for close functions
+			select {
+			case <-ch:
+				c.forceExit()
+			case <-c.done:
+			}
 		}()
 	}
 	return c
 }
 
+// Add adds callback to DefaultStage
 func (c *Closer) Add(name string, f func() error) {
+	c.AddWithStage(name, DefaultStage, f)
+}
+
+func (c *Closer) AddWithStage(name string, stage Stage, f func() error) {
+	c.AddContext(name, stage, 0, func(context.Context) error {
+		return f()
+	})
+}
+
+// AddContext adds callback which receives context with deadline, timeout 0 means default timeout of Closer
+func (c *Closer) AddContext(name string, stage Stage, timeout time.Duration, f func(ctx context.Context) error) {
 	defer c.Unlock()
 	c.Lock()
 	if _, ok := c.funcs[name]; ok {
 		panic("Closer " + name + " already used")
 	}
-	c.funcs[name] = f
+	c.funcs[name] = closeFunc{stage: stage, timeout: timeout, fn: f}
+}
+
+// SetTimeout sets default deadline of every close function and deadline of whole CloseAll
+func (c *Closer) SetTimeout(funcTimeout, totalTimeout time.Duration) {
+	defer c.Unlock()
+	c.Lock()
+	c.funcTimeout = funcTimeout
+	c.totalTimeout = totalTimeout
 }
 
 func (c *Closer) Wait() {
@@ -60,26 +182,144 @@ func (c *Closer) Wait() {
 	}
 }
 
+// WaitContext waits until all resources are closed or ctx is done
+func (c *Closer) WaitContext(ctx context.Context) error {
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pending returns names of close functions which are not finished yet
+func (c *Closer) Pending() []string {
+	defer c.Unlock()
+	c.Lock()
+	ret := make([]string, 0, len(c.pending))
+	for name := range c.pending {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func (c *Closer) forceExit() {
+	logger.Error(logger.App, "closer: forced shutdown, pending: %s", strings.Join(c.Pending(), ", "))
+	exit(1)
+}
+
+func (c *Closer) finished(name string) {
+	c.Lock()
+	delete(c.pending, name)
+	c.Unlock()
+}
+
+// Closing returns channel which is closed as soon as CloseAll is started
+func (c *Closer) Closing() <-chan struct{} {
+	return c.closing
+}
+
 func (c *Closer) CloseAll() {
 	c.once.Do(func() {
 		defer close(c.done)
+		close(c.closing)
 
 		c.Lock()
 		funcs := c.funcs
 		c.funcs = nil
+		c.pending = make(map[string]struct{}, len(funcs))
+		for name := range funcs {
+			c.pending[name] = struct{}{}
+		}
+		funcTimeout, totalTimeout := c.funcTimeout, c.totalTimeout
 		c.Unlock()
 
-		var wg sync.WaitGroup
-		wg.Add(len(funcs))
-		for name, fn := range funcs {
-			go func(name string, fn func() error) {
-				defer wg.Done()
-				if err := fn(); err != nil {
-					fmt.Printf("Error on close %s: %+v", name, err)
+		stageFuncs := make(map[Stage]map[string]closeFunc)
+		stages := make([]Stage, 0, 4)
+		for name, f := range funcs {
+			if f.timeout <= 0 {
+				f.timeout = funcTimeout
+			}
+			if _, ok := stageFuncs[f.stage]; !ok {
+				stageFuncs[f.stage] = make(map[string]closeFunc)
+				stages = append(stages, f.stage)
+			}
+			stageFuncs[f.stage][name] = f
+		}
+		sort.Slice(stages, func(i, j int) bool { return stages[i] < stages[j] })
+
+		ctx, cancel := context.WithTimeout(context.Background(), totalTimeout)
+		defer cancel()
+
+		var failures []Failure
+		for _, stage := range stages {
+			if ctx.Err() != nil {
+				for name := range stageFuncs[stage] {
+					logger.Error(logger.App, "closer: %s not started, global deadline exceeded", name)
+					failures = append(failures, Failure{Name: name, Err: ctx.Err(), Timeout: true})
 				}
-			}(name, fn)
+				continue
+			}
+			failures = append(failures, c.closeStage(ctx, stageFuncs[stage])...)
 		}
 
-		wg.Wait()
+		if len(failures) > 0 {
+			sort.Slice(failures, func(i, j int) bool { return failures[i].Name < failures[j].Name })
+			c.err = &Error{Failures: failures}
+		}
 	})
 }
+
+// CloseAllWithResult calls CloseAll (or waits already started one) and returns *Error if any close function failed
+func (c *Closer) CloseAllWithResult() error {
+	c.CloseAll()
+	return c.err
+}
+
+func (c *Closer) closeStage(ctx context.Context, funcs map[string]closeFunc) []Failure {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []Failure
+	)
+	wg.Add(len(funcs))
+	for name, f := range funcs {
+		go func(name string, f closeFunc) {
+			defer wg.Done()
+			defer c.finished(name)
+			if failure := closeFunction(ctx, name, f); failure != nil {
+				mu.Lock()
+				failures = append(failures, *failure)
+				mu.Unlock()
+			}
+		}(name, f)
+	}
+
+	wg.Wait()
+	return failures
+}
+
+func closeFunction(ctx context.Context, name string, f closeFunc) *Failure {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- f.fn(ctx)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			logger.Error(logger.App, "closer: %s failed in %dms: %+v", name, time.Since(start).Milliseconds(), err)
+			return &Failure{Name: name, Err: err}
+		}
+		logger.Info(logger.App, "closer: %s closed in %dms", name, time.Since(start).Milliseconds())
+		return nil
+	case <-ctx.Done():
+		logger.Error(logger.App, "closer: %s timed out after %dms", name, time.Since(start).Milliseconds())
+		return &Failure{Name: name, Err: ctx.Err(), Timeout: true}
+	}
+}
@@ -0,0 +1,132 @@
+package closer
+
+import (
+	"context"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloser(t *testing.T) {
+	t.Run("StagesOrder", func(t *testing.T) {
+		var (
+			mu    sync.Mutex
+			order []string
+		)
+		add := func(c *Closer, name string, stage Stage) {
+			c.AddWithStage(name, stage, func() error {
+				mu.Lock()
+				order = append(order, name)
+				mu.Unlock()
+				return nil
+			})
+		}
+
+		c := New()
+		add(c, "tracing", StageTelemetry)
+		add(c, "db", StageClients)
+		add(c, "grpc", StageServers)
+		add(c, "consumer", StageWorkers)
+		add(c, "http", StageServers)
+		c.CloseAll()
+		c.Wait()
+
+		assert.Len(t, order, 5)
+		assert.ElementsMatch(t, []string{"grpc", "http"}, order[:2])
+		assert.Equal(t, []string{"consumer", "db", "tracing"}, order[2:])
+	})
+
+	t.Run("Closing", func(t *testing.T) {
+		c := New()
+		c.Add("check", func() error {
+			select {
+			case <-c.Closing():
+			default:
+				t.Error("Closing channel should be closed before close functions")
+			}
+			return nil
+		})
+		c.CloseAll()
+	})
+
+	t.Run("Result", func(t *testing.T) {
+		c := New()
+		c.SetTimeout(20*time.Millisecond, time.Second)
+		c.Add("ok", func() error { return nil })
+		c.Add("failed", func() error { return context.Canceled })
+		c.AddContext("hang", StageServers, 0, func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		})
+
+		err := c.CloseAllWithResult()
+		closerErr, ok := err.(*Error)
+		if !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, []Failure{
+			{Name: "failed", Err: context.Canceled},
+			{Name: "hang", Err: context.DeadlineExceeded, Timeout: true},
+		}, closerErr.Failures)
+		assert.Equal(t, "closer: failed: context canceled; hang: timeout", err.Error())
+	})
+
+	t.Run("GlobalDeadline", func(t *testing.T) {
+		c := New()
+		c.SetTimeout(time.Second, 20*time.Millisecond)
+		c.AddContext("slow", StageServers, 0, func(ctx context.Context) error {
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		})
+		c.Add("db", func() error { return nil })
+
+		err := c.CloseAllWithResult()
+		closerErr, ok := err.(*Error)
+		if !assert.True(t, ok) {
+			return
+		}
+		assert.Len(t, closerErr.Failures, 2)
+	})
+
+	t.Run("WaitContext", func(t *testing.T) {
+		c := New()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.Equal(t, context.DeadlineExceeded, c.WaitContext(ctx))
+
+		c.CloseAll()
+		assert.Nil(t, c.WaitContext(context.Background()))
+	})
+
+	t.Run("ForceExit", func(t *testing.T) {
+		exitCode := make(chan int, 1)
+		exit = func(code int) { exitCode <- code }
+		defer func() { exit = os.Exit }()
+
+		c := New(syscall.SIGUSR1)
+		release := make(chan struct{})
+		defer close(release)
+		c.Add("hang", func() error {
+			<-release
+			return nil
+		})
+
+		time.Sleep(10 * time.Millisecond)
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+		<-c.Closing()
+		assert.Eventually(t, func() bool { return len(c.Pending()) == 1 }, time.Second, time.Millisecond)
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+
+		select {
+		case code := <-exitCode:
+			assert.Equal(t, 1, code)
+			assert.Equal(t, []string{"hang"}, c.Pending())
+		case <-time.After(time.Second):
+			t.Error("second signal should force exit")
+		}
+	})
+}
@@ -3,6 +3,7 @@ module github.com/sanches1984/gopkg-app
 go 1.15
 
 require (
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/getsentry/sentry-go v0.7.0
 	github.com/go-chi/chi v4.1.2+incompatible
 	github.com/go-chi/cors v1.1.1
@@ -11,15 +12,17 @@ require (
 	github.com/go-playground/validator/v10 v10.3.0
 	github.com/gocraft/work v0.5.1
 	github.com/gogo/protobuf v1.3.1
+	github.com/golang/protobuf v1.5.2
 	github.com/gomodule/redigo v1.8.2
 	github.com/grpc-ecosystem/go-grpc-middleware v1.2.1
 	github.com/grpc-ecosystem/go-grpc-prometheus v1.2.0
-	github.com/grpc-ecosystem/grpc-gateway v1.14.8
+	github.com/grpc-ecosystem/grpc-gateway v1.16.0
 	github.com/joho/godotenv v1.3.0
 	github.com/k3a/html2text v0.0.0-20191003111652-62431c4a3ba5
 	github.com/mitchellh/go-server-timing v1.0.1
 	github.com/opentracing/opentracing-go v1.2.0
 	github.com/prometheus/client_golang v1.7.1
+	github.com/prometheus/client_model v0.2.0
 	github.com/robfig/cron v1.2.0 // indirect
 	github.com/sanches1984/gopkg-database v1.0.0
 	github.com/sanches1984/gopkg-errors v1.0.3
@@ -31,6 +34,10 @@ require (
 	github.com/uber/jaeger-client-go v2.25.0+incompatible
 	github.com/uber/jaeger-lib v2.2.0+incompatible // indirect
 	github.com/utrack/clay/v2 v2.4.9
-	google.golang.org/grpc v1.31.1
+	go.opentelemetry.io/proto/otlp v0.9.0
+	golang.org/x/net v0.0.0-20200822124328-c89045814202
+	google.golang.org/grpc v1.37.1
+	google.golang.org/protobuf v1.26.0
 	gopkg.in/satori/go.uuid.v1 v1.2.0
+	gopkg.in/yaml.v2 v2.3.0
 )
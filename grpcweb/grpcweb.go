@@ -0,0 +1,294 @@
+// Package grpcweb serves gRPC-Web requests of browser clients by grpc.Server
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/cors"
+	"google.golang.org/grpc"
+)
+
+const (
+	contentTypeWeb     = "application/grpc-web"
+	contentTypeWebText = "application/grpc-web-text"
+	contentTypeGRPC    = "application/grpc"
+
+	// trailerFlag marks trailers frame in response body
+	trailerFlag = 0x80
+)
+
+// defaultAllowedHeaders are sent by grpc-web clients, other grpc metadata is allowed with WithAllowedHeaders
+var defaultAllowedHeaders = []string{"Content-Type", "X-Grpc-Web", "X-User-Agent", "Grpc-Timeout", "Authorization"}
+
+// Handler translates gRPC-Web requests (binary and text modes) to grpc.Server.ServeHTTP,
+// so calls pass the same interceptors as native grpc. Other requests are passed to next handler
+type Handler struct {
+	server   *grpc.Server
+	next     http.Handler
+	services map[string]struct{}
+	grpcWeb  http.Handler
+	cors     bool
+}
+
+type Option func(o *options)
+
+type options struct {
+	allowedOrigins []string
+	allowedHeaders []string
+}
+
+// WithAllowedOrigins allows cross-origin calls from origins, by default only same-origin calls are served.
+// Credentials (cookies) are allowed for listed origins, "*" allows all origins without credentials
+func WithAllowedOrigins(origins ...string) Option {
+	return func(o *options) {
+		o.allowedOrigins = origins
+	}
+}
+
+// WithAllowedHeaders allows cross-origin calls to send headers (grpc metadata) in addition to grpc-web ones
+func WithAllowedHeaders(headers ...string) Option {
+	return func(o *options) {
+		o.allowedHeaders = append(o.allowedHeaders, headers...)
+	}
+}
+
+// New creates handler for services registered in server, it should be called after registration of services
+func New(server *grpc.Server, next http.Handler, opts ...Option) *Handler {
+	o := &options{allowedHeaders: defaultAllowedHeaders}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	h := &Handler{
+		server:   server,
+		next:     next,
+		services: make(map[string]struct{}),
+	}
+	for name := range server.GetServiceInfo() {
+		h.services[name] = struct{}{}
+	}
+	h.grpcWeb = http.HandlerFunc(h.serveGRPCWeb)
+	// cors allows all origins for empty list, so CORS handler is used only with origins set
+	if len(o.allowedOrigins) > 0 {
+		h.cors = true
+		h.grpcWeb = cors.New(cors.Options{
+			AllowedOrigins:   o.allowedOrigins,
+			AllowedMethods:   []string{http.MethodPost},
+			AllowedHeaders:   o.allowedHeaders,
+			ExposedHeaders:   []string{"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin"},
+			AllowCredentials: !allowsAll(o.allowedOrigins),
+			MaxAge:           300,
+		}).Handler(http.HandlerFunc(h.serveGRPCWeb))
+	}
+	return h
+}
+
+// allowsAll reports wildcard origin, credentials are disabled with it
+func allowsAll(origins []string) bool {
+	for _, origin := range origins {
+		if origin == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGRPCWebRequest matches POST with application/grpc-web or application/grpc-web-text content type
+func IsGRPCWebRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeWeb)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if IsGRPCWebRequest(r) || (h.cors && r.Method == http.MethodOptions && h.isService(r.URL.Path)) {
+		h.grpcWeb.ServeHTTP(w, r)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// isService checks path /package.Service/Method
+func (h *Handler) isService(path string) bool {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) != 2 {
+		return false
+	}
+	_, ok := h.services[parts[0]]
+	return ok
+}
+
+func (h *Handler) serveGRPCWeb(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	text := strings.HasPrefix(contentType, contentTypeWebText)
+
+	req := r.Clone(r.Context())
+	req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/2", 2, 0
+	req.Header.Set("Content-Type", toGRPCContentType(contentType))
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+	if text {
+		req.Body = ioutil.NopCloser(&textReader{r: r.Body})
+	}
+
+	rw := &responseWriter{w: w, header: make(http.Header), text: text}
+	h.server.ServeHTTP(rw, req)
+	rw.finish()
+}
+
+// textReader decodes body of text mode. Clients may pad every chunk of body separately,
+// so body is decoded by 4 byte quanta: base64.NewDecoder stops at the first padding
+type textReader struct {
+	r   io.Reader
+	buf [512]byte
+	in  []byte
+	out []byte
+	err error
+}
+
+func (t *textReader) Read(p []byte) (int, error) {
+	for len(t.out) == 0 {
+		if t.err != nil {
+			if t.err == io.EOF && len(t.in) > 0 {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, t.err
+		}
+		n, err := t.r.Read(t.buf[:])
+		t.err = err
+		for _, c := range t.buf[:n] {
+			if c != '\r' && c != '\n' {
+				t.in = append(t.in, c)
+			}
+		}
+		full := len(t.in) / 4 * 4
+		for i := 0; i < full; i += 4 {
+			var quantum [3]byte
+			decoded, err := base64.StdEncoding.Decode(quantum[:], t.in[i:i+4])
+			if err != nil {
+				t.err = err
+				break
+			}
+			t.out = append(t.out, quantum[:decoded]...)
+		}
+		t.in = append(t.in[:0], t.in[full:]...)
+	}
+	n := copy(p, t.out)
+	t.out = t.out[n:]
+	return n, nil
+}
+
+func toGRPCContentType(contentType string) string {
+	if strings.HasPrefix(contentType, contentTypeWebText) {
+		return contentTypeGRPC + strings.TrimPrefix(contentType, contentTypeWebText)
+	}
+	return contentTypeGRPC + strings.TrimPrefix(contentType, contentTypeWeb)
+}
+
+func toWebContentType(contentType string, text bool) string {
+	if text {
+		return contentTypeWebText + strings.TrimPrefix(contentType, contentTypeGRPC)
+	}
+	return contentTypeWeb + strings.TrimPrefix(contentType, contentTypeGRPC)
+}
+
+// responseWriter moves HTTP/2 trailers written by grpc to the end of body, text mode encodes body with base64
+type responseWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	text        bool
+	wroteHeader bool
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+
+	trailers := rw.declaredTrailers()
+	dst := rw.w.Header()
+	for key, values := range rw.header {
+		if _, ok := trailers[key]; ok || key == "Trailer" || strings.HasPrefix(key, http.TrailerPrefix) {
+			continue
+		}
+		dst[key] = values
+	}
+	dst.Set("Content-Type", toWebContentType(rw.header.Get("Content-Type"), rw.text))
+	dst.Del("Content-Length")
+	rw.w.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.text {
+		// every chunk is padded separately, clients decode concatenated base64 chunks
+		if _, err := rw.w.Write([]byte(base64.StdEncoding.EncodeToString(b))); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	return rw.w.Write(b)
+}
+
+// Flush is required by grpc.Server.ServeHTTP
+func (rw *responseWriter) Flush() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rw *responseWriter) declaredTrailers() map[string]struct{} {
+	ret := make(map[string]struct{})
+	for _, value := range rw.header.Values("Trailer") {
+		for _, key := range strings.Split(value, ",") {
+			ret[http.CanonicalHeaderKey(strings.TrimSpace(key))] = struct{}{}
+		}
+	}
+	return ret
+}
+
+// finish writes trailers frame: flag byte, 4 bytes length and "key: value\r\n" lines
+func (rw *responseWriter) finish() {
+	lines := make([]string, 0, 4)
+	for key := range rw.declaredTrailers() {
+		for _, value := range rw.header.Values(key) {
+			lines = append(lines, strings.ToLower(key)+": "+value+"\r\n")
+		}
+	}
+	for key, values := range rw.header {
+		if !strings.HasPrefix(key, http.TrailerPrefix) {
+			continue
+		}
+		for _, value := range values {
+			lines = append(lines, strings.ToLower(strings.TrimPrefix(key, http.TrailerPrefix))+": "+value+"\r\n")
+		}
+	}
+	sort.Strings(lines)
+
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(line)
+	}
+	frame := make([]byte, 5, 5+buf.Len())
+	frame[0] = trailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(buf.Len()))
+	frame = append(frame, buf.Bytes()...)
+
+	_, _ = rw.Write(frame)
+	rw.Flush()
+}
@@ -0,0 +1,199 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func frame(flag byte, data []byte) []byte {
+	ret := make([]byte, 5, 5+len(data))
+	ret[0] = flag
+	binary.BigEndian.PutUint32(ret[1:], uint32(len(data)))
+	return append(ret, data...)
+}
+
+// readFrames returns message and trailers of grpc-web response
+func readFrames(t *testing.T, body []byte) ([]byte, string) {
+	var message []byte
+	var trailers string
+	for len(body) >= 5 {
+		size := binary.BigEndian.Uint32(body[1:5])
+		data := body[5 : 5+size]
+		if body[0]&trailerFlag != 0 {
+			trailers = string(data)
+		} else {
+			message = data
+		}
+		body = body[5+size:]
+	}
+	assert.Len(t, body, 0)
+	return message, trailers
+}
+
+// decodeText decodes response of text mode, chunks are padded separately, so every 4 chars are decoded separately
+func decodeText(t *testing.T, body []byte) []byte {
+	var decoded []byte
+	for ; len(body) >= 4; body = body[4:] {
+		chunk, err := base64.StdEncoding.DecodeString(string(body[:4]))
+		if !assert.Nil(t, err) {
+			return nil
+		}
+		decoded = append(decoded, chunk...)
+	}
+	return decoded
+}
+
+func TestHandler(t *testing.T) {
+	server := grpc.NewServer()
+	healthServer := grpchealth.NewServer()
+	healthServer.SetServingStatus("app", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	httpServer := httptest.NewServer(New(server, next, WithAllowedOrigins("https://example.com")))
+	defer httpServer.Close()
+	sameOriginServer := httptest.NewServer(New(server, next))
+	defer sameOriginServer.Close()
+	wildcardServer := httptest.NewServer(New(server, next, WithAllowedOrigins("*")))
+	defer wildcardServer.Close()
+
+	send := func(t *testing.T, url, contentType, origin string, body []byte) (*http.Response, []byte) {
+		req, _ := http.NewRequest(http.MethodPost, url+"/grpc.health.v1.Health/Check", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Origin", origin)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return resp, respBody
+	}
+	request := func(service string) []byte {
+		msg, _ := proto.Marshal(&healthpb.HealthCheckRequest{Service: service})
+		return frame(0, msg)
+	}
+	call := func(t *testing.T, contentType, service string) (*http.Response, []byte) {
+		body := request(service)
+		if strings.HasPrefix(contentType, contentTypeWebText) {
+			body = []byte(base64.StdEncoding.EncodeToString(body))
+		}
+		return send(t, httpServer.URL, contentType, "https://example.com", body)
+	}
+	preflight := func(t *testing.T, url, origin string) *http.Response {
+		req, _ := http.NewRequest(http.MethodOptions, url+"/grpc.health.v1.Health/Check", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "x-grpc-web, content-type")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("Binary", func(t *testing.T) {
+		resp, body := call(t, "application/grpc-web+proto", "app")
+		assert.Equal(t, "application/grpc-web+proto", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "https://example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+
+		message, trailers := readFrames(t, body)
+		checkResp := &healthpb.HealthCheckResponse{}
+		assert.Nil(t, proto.Unmarshal(message, checkResp))
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, checkResp.Status)
+		assert.Contains(t, trailers, "grpc-status: 0\r\n")
+	})
+
+	t.Run("Text", func(t *testing.T) {
+		resp, body := call(t, "application/grpc-web-text", "unknown")
+		assert.Equal(t, "application/grpc-web-text", resp.Header.Get("Content-Type"))
+
+		_, trailers := readFrames(t, decodeText(t, body))
+		assert.Contains(t, trailers, "grpc-status: 5\r\n")
+	})
+
+	t.Run("TextPaddedChunks", func(t *testing.T) {
+		body := request("app")
+		// header and message are sent as separately padded chunks
+		text := base64.StdEncoding.EncodeToString(body[:4]) + base64.StdEncoding.EncodeToString(body[4:])
+		assert.Contains(t, text[:8], "=")
+		_, respBody := send(t, httpServer.URL, "application/grpc-web-text", "https://example.com", []byte(text))
+
+		message, trailers := readFrames(t, decodeText(t, respBody))
+		checkResp := &healthpb.HealthCheckResponse{}
+		assert.Nil(t, proto.Unmarshal(message, checkResp))
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, checkResp.Status)
+		assert.Contains(t, trailers, "grpc-status: 0\r\n")
+	})
+
+	t.Run("Preflight", func(t *testing.T) {
+		resp := preflight(t, httpServer.URL, "https://example.com")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "https://example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("DisallowedOrigin", func(t *testing.T) {
+		resp, _ := send(t, httpServer.URL, "application/grpc-web+proto", "https://evil.com", request("app"))
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"))
+
+		resp = preflight(t, httpServer.URL, "https://evil.com")
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("SameOriginByDefault", func(t *testing.T) {
+		resp, body := send(t, sameOriginServer.URL, "application/grpc-web+proto", "https://example.com", request("app"))
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+		_, trailers := readFrames(t, body)
+		assert.Contains(t, trailers, "grpc-status: 0\r\n")
+
+		resp = preflight(t, sameOriginServer.URL, "https://example.com")
+		assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("WildcardWithoutCredentials", func(t *testing.T) {
+		resp, _ := send(t, wildcardServer.URL, "application/grpc-web+proto", "https://example.com", request("app"))
+		assert.NotEmpty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("Next", func(t *testing.T) {
+		resp, err := http.Get(httpServer.URL + "/grpc.health.v1.Health/Check")
+		if !assert.Nil(t, err) {
+			return
+		}
+		resp.Body.Close()
+		assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+	})
+}
+
+func TestTextReader(t *testing.T) {
+	data := []byte("grpc-web text body")
+	text := base64.StdEncoding.EncodeToString(data[:1]) + "\r\n" +
+		base64.StdEncoding.EncodeToString(data[1:5]) + base64.StdEncoding.EncodeToString(data[5:])
+	decoded, err := ioutil.ReadAll(&textReader{r: strings.NewReader(text)})
+	assert.Nil(t, err)
+	assert.Equal(t, data, decoded)
+
+	_, err = ioutil.ReadAll(&textReader{r: strings.NewReader("Z3Jw*")})
+	assert.NotNil(t, err)
+	_, err = ioutil.ReadAll(&textReader{r: strings.NewReader("Z3JwYw")})
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
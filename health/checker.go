@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+	errors "github.com/sanches1984/gopkg-errors"
+	"github.com/streadway/amqp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// Pinger is implemented by *sql.DB and most database drivers
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Ping checks database connection
+func Ping(db Pinger) CheckFn {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// RedisPool checks redis with PING command from pool connection
+func RedisPool(pool *redis.Pool) CheckFn {
+	return func(ctx context.Context) error {
+		conn, err := pool.GetContext(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		_, err = conn.Do("PING")
+		return err
+	}
+}
+
+// AMQPChannel fails after channel or its connection was closed
+func AMQPChannel(channel *amqp.Channel) CheckFn {
+	var (
+		mu       sync.Mutex
+		closeErr error
+		closed   bool
+	)
+	notify := channel.NotifyClose(make(chan *amqp.Error, 1))
+	go func() {
+		err, ok := <-notify
+		mu.Lock()
+		closed = true
+		if ok && err != nil {
+			closeErr = err
+		}
+		mu.Unlock()
+	}()
+
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			return nil
+		}
+		if closeErr != nil {
+			return errors.Internal.ErrWrap(ctx, "amqp channel closed", closeErr)
+		}
+		return errors.Internal.Err(ctx, "amqp channel closed")
+	}
+}
+
+// GRPCConn fails when client connection is in transient failure or shut down
+func GRPCConn(conn *grpc.ClientConn) CheckFn {
+	return func(ctx context.Context) error {
+		state := conn.GetState()
+		switch state {
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return errors.Internal.Err(ctx, "grpc connection is "+state.String()).WithLogKV("target", conn.Target())
+		}
+		return nil
+	}
+}
@@ -0,0 +1,231 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	errors "github.com/sanches1984/gopkg-errors"
+)
+
+const (
+	DefaultTimeout = 3 * time.Second
+
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFn returns error when dependency is not available
+type CheckFn func(ctx context.Context) error
+
+type CheckOption func(c *check)
+
+// WithTimeout limits execution time of one check
+func WithTimeout(timeout time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = timeout
+	}
+}
+
+// WithCacheTTL reuses last result of check during ttl, so probes don't overload dependency
+func WithCacheTTL(ttl time.Duration) CheckOption {
+	return func(c *check) {
+		c.cacheTTL = ttl
+	}
+}
+
+// WithLiveness adds check to liveness probe, by default checks affect readiness only
+func WithLiveness() CheckOption {
+	return func(c *check) {
+		c.liveness = true
+	}
+}
+
+// Result of one check
+type Result struct {
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// Report is response of probe endpoints
+type Report struct {
+	Status string            `json:"status"`
+	Reason string            `json:"reason,omitempty"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+type check struct {
+	sync.Mutex
+	name     string
+	fn       CheckFn
+	timeout  time.Duration
+	cacheTTL time.Duration
+	liveness bool
+	last     *Result
+}
+
+func (c *check) run(ctx context.Context) Result {
+	c.Lock()
+	defer c.Unlock()
+	if c.last != nil && c.cacheTTL > 0 && time.Since(c.last.CheckedAt) < c.cacheTTL {
+		return *c.last
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errCh <- errors.Internal.Err(ctx, "health: check panic").WithLogKV("check", c.name, "panic", r)
+			}
+		}()
+		errCh <- c.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res := Result{Status: StatusOK, DurationMs: time.Since(start).Milliseconds(), CheckedAt: start}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	c.last = &res
+	return res
+}
+
+// Registry keeps health checks and serves liveness and readiness probes
+type Registry struct {
+	mu           sync.RWMutex
+	checks       []*check
+	shuttingDown bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Add registers check, name should be unique
+func (r *Registry) Add(name string, fn CheckFn, option ...CheckOption) error {
+	if fn == nil {
+		return errors.Internal.Err(context.Background(), "health: empty check").WithPayloadKV("name", name)
+	}
+	c := &check{name: name, fn: fn, timeout: DefaultTimeout}
+	for _, o := range option {
+		o(c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, exists := range r.checks {
+		if exists.name == name {
+			return errors.Internal.Err(context.Background(), "health: check already used").WithPayloadKV("name", name)
+		}
+	}
+	r.checks = append(r.checks, c)
+	return nil
+}
+
+// SetShuttingDown marks application as draining, readiness fails from this moment
+func (r *Registry) SetShuttingDown() {
+	r.mu.Lock()
+	r.shuttingDown = true
+	r.mu.Unlock()
+}
+
+// Live runs checks marked WithLiveness
+func (r *Registry) Live(ctx context.Context) Report {
+	return r.run(ctx, true)
+}
+
+// Ready runs all checks, fails without running checks during shutdown
+func (r *Registry) Ready(ctx context.Context) Report {
+	r.mu.RLock()
+	shuttingDown := r.shuttingDown
+	r.mu.RUnlock()
+	if shuttingDown {
+		return Report{Status: StatusFail, Reason: "shutting down"}
+	}
+	return r.run(ctx, false)
+}
+
+func (r *Registry) run(ctx context.Context, livenessOnly bool) Report {
+	r.mu.RLock()
+	checks := make([]*check, 0, len(r.checks))
+	for _, c := range r.checks {
+		if !livenessOnly || c.liveness {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	wg.Add(len(checks))
+	for _, c := range checks {
+		go func(c *check) {
+			defer wg.Done()
+			res := c.run(ctx)
+			mu.Lock()
+			report.Checks[c.name] = res
+			if res.Status != StatusOK {
+				report.Status = StatusFail
+			}
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+	return report
+}
+
+// Names returns names of registered checks
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ret := make([]string, 0, len(r.checks))
+	for _, c := range r.checks {
+		ret = append(ret, c.name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// LiveHandler serves liveness probe
+func (r *Registry) LiveHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		writeReport(w, r.Live(req.Context()))
+	}
+}
+
+// ReadyHandler serves readiness probe
+func (r *Registry) ReadyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		writeReport(w, r.Ready(req.Context()))
+	}
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache, no-store")
+	if report.Status == StatusOK {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,66 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("Ready", func(t *testing.T) {
+		r := NewRegistry()
+		assert.Nil(t, r.Add("ok", func(ctx context.Context) error { return nil }))
+		assert.NotNil(t, r.Add("ok", func(ctx context.Context) error { return nil }))
+		assert.Nil(t, r.Add("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, WithTimeout(10*time.Millisecond)))
+
+		report := r.Ready(context.Background())
+		assert.Equal(t, StatusFail, report.Status)
+		assert.Equal(t, StatusOK, report.Checks["ok"].Status)
+		assert.Equal(t, StatusFail, report.Checks["slow"].Status)
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+	})
+
+	t.Run("Live", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Add("db", func(ctx context.Context) error { return context.Canceled })
+		_ = r.Add("process", func(ctx context.Context) error { return nil }, WithLiveness())
+
+		report := r.Live(context.Background())
+		assert.Equal(t, StatusOK, report.Status)
+		assert.Len(t, report.Checks, 1)
+	})
+
+	t.Run("Cache", func(t *testing.T) {
+		r := NewRegistry()
+		calls := 0
+		_ = r.Add("cached", func(ctx context.Context) error {
+			calls++
+			return nil
+		}, WithCacheTTL(time.Minute))
+
+		r.Ready(context.Background())
+		r.Ready(context.Background())
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("ShuttingDown", func(t *testing.T) {
+		r := NewRegistry()
+		_ = r.Add("ok", func(ctx context.Context) error { return nil })
+		r.SetShuttingDown()
+
+		w := httptest.NewRecorder()
+		r.ReadyHandler()(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		w = httptest.NewRecorder()
+		r.LiveHandler()(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -0,0 +1,59 @@
+package loglevel
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	logger "github.com/sanches1984/gopkg-logger"
+)
+
+type levelResponse struct {
+	Level     string     `json:"level"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Handler returns current level on GET, PUT or POST changes it: level=debug|info|error and optional ttl=10m
+func Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			level, err := ParseLevel(r.FormValue("level"))
+			if err != nil {
+				writeLevel(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			var ttl time.Duration
+			if s := r.FormValue("ttl"); s != "" {
+				if ttl, err = time.ParseDuration(s); err != nil || ttl < 0 {
+					writeLevel(w, http.StatusBadRequest, "invalid ttl")
+					return
+				}
+			}
+			if err := Set(level, ttl); err != nil {
+				logger.Error(logger.App, "%v", err)
+				writeLevel(w, http.StatusInternalServerError, "can't change logger level")
+				return
+			}
+			logger.Info(logger.App, "Log level is changed to %s by %s, ttl %s", level, r.RemoteAddr, ttl)
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			writeLevel(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeLevel(w, http.StatusOK, "")
+	}
+}
+
+func writeLevel(w http.ResponseWriter, status int, errMsg string) {
+	level, expiresAt := Get()
+	resp := levelResponse{Level: level.String(), Error: errMsg}
+	if !expiresAt.IsZero() {
+		resp.ExpiresAt = &expiresAt
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
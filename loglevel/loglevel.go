@@ -0,0 +1,146 @@
+// Package loglevel controls verbosity of app logs at runtime.
+// Level is applied to gopkg-logger by setter registered with SetLoggerSetter, log middleware and interceptors
+// check level of this package too: info logs are skipped on error level, debug logs are written with info level
+// of gopkg-logger when it is not switched to debug.
+package loglevel
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	errors "github.com/sanches1984/gopkg-errors"
+	logger "github.com/sanches1984/gopkg-logger"
+)
+
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel parses debug, info or error
+func ParseLevel(s string) (Level, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return level, nil
+		}
+	}
+	return LevelInfo, errors.BadRequest.Err(context.Background(), "Unknown log level").WithPayloadKV("level", s)
+}
+
+// LoggerSetter applies level to gopkg-logger
+type LoggerSetter func(level Level) error
+
+var (
+	mu           sync.RWMutex
+	loggerSetter LoggerSetter
+	current      = LevelInfo
+	base         = LevelInfo
+	expiresAt    time.Time
+	revert       *time.Timer
+)
+
+// Get returns current level and time of revert, zero time if level is not temporary
+func Get() (Level, time.Time) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current, expiresAt
+}
+
+// SetLoggerSetter sets function which changes level of gopkg-logger, it is called on every Set and revert
+func SetLoggerSetter(setter LoggerSetter) {
+	mu.Lock()
+	defer mu.Unlock()
+	loggerSetter = setter
+}
+
+func applyLogger(level Level) error {
+	if loggerSetter == nil {
+		return nil
+	}
+	if err := loggerSetter(level); err != nil {
+		return errors.Internal.ErrWrap(context.Background(), "Can't change logger level", err).
+			WithPayloadKV("level", level.String())
+	}
+	return nil
+}
+
+// Set changes level, with ttl > 0 level is reverted to the last permanent level after ttl.
+// Level is not changed when logger setter fails
+func Set(level Level, ttl time.Duration) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if err := applyLogger(level); err != nil {
+		return err
+	}
+	if revert != nil {
+		revert.Stop()
+		revert = nil
+	}
+	current = level
+	expiresAt = time.Time{}
+	if ttl <= 0 {
+		base = level
+		return nil
+	}
+	expiresAt = time.Now().Add(ttl)
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		// level was changed again
+		if revert != timer {
+			return
+		}
+		current, expiresAt, revert = base, time.Time{}, nil
+		if err := applyLogger(base); err != nil {
+			logger.Error(logger.App, "Log level is not reverted to %s: %v", base, err)
+			return
+		}
+		logger.Info(logger.App, "Log level is reverted to %s", base)
+	})
+	revert = timer
+	return nil
+}
+
+var forcedDebugKey = new(struct{})
+
+// WithForcedDebug enables debug logs for request regardless of current level
+func WithForcedDebug(ctx context.Context) context.Context {
+	return context.WithValue(ctx, &forcedDebugKey, true)
+}
+
+func isForcedDebug(ctx context.Context) bool {
+	v, _ := ctx.Value(&forcedDebugKey).(bool)
+	return v
+}
+
+// Enabled checks that logs of level should be written for request
+func Enabled(ctx context.Context, level Level) bool {
+	if ctx != nil && isForcedDebug(ctx) {
+		return true
+	}
+	current, _ := Get()
+	return level >= current
+}
+
+// Debug writes log if debug is enabled for request
+func Debug(ctx context.Context, format string, args ...interface{}) {
+	if Enabled(ctx, LevelDebug) {
+		logger.Info(ctx, "debug: "+format, args...)
+	}
+}
@@ -0,0 +1,114 @@
+package loglevel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevel(t *testing.T) {
+	defer Set(LevelInfo, 0)
+
+	t.Run("Parse", func(t *testing.T) {
+		level, err := ParseLevel("DEBUG")
+		assert.Nil(t, err)
+		assert.Equal(t, LevelDebug, level)
+		_, err = ParseLevel("trace")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		Set(LevelError, 0)
+		ctx := context.Background()
+		assert.False(t, Enabled(ctx, LevelInfo))
+		assert.True(t, Enabled(ctx, LevelError))
+		assert.True(t, Enabled(WithForcedDebug(ctx), LevelDebug))
+	})
+
+	t.Run("Revert", func(t *testing.T) {
+		Set(LevelInfo, 0)
+		Set(LevelDebug, 20*time.Millisecond)
+		level, expiresAt := Get()
+		assert.Equal(t, LevelDebug, level)
+		assert.False(t, expiresAt.IsZero())
+
+		assert.Eventually(t, func() bool {
+			level, _ := Get()
+			return level == LevelInfo
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("LoggerSetter", func(t *testing.T) {
+		Set(LevelInfo, 0)
+		var mu sync.Mutex
+		var applied []Level
+		SetLoggerSetter(func(level Level) error {
+			mu.Lock()
+			defer mu.Unlock()
+			applied = append(applied, level)
+			return nil
+		})
+		defer SetLoggerSetter(nil)
+
+		assert.Nil(t, Set(LevelDebug, 20*time.Millisecond))
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(applied) == 2
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, []Level{LevelDebug, LevelInfo}, applied)
+	})
+
+	t.Run("LoggerSetterError", func(t *testing.T) {
+		Set(LevelInfo, 0)
+		SetLoggerSetter(func(level Level) error {
+			return fmt.Errorf("unsupported")
+		})
+		defer SetLoggerSetter(nil)
+
+		assert.NotNil(t, Set(LevelDebug, 0))
+		level, _ := Get()
+		assert.Equal(t, LevelInfo, level)
+
+		w := httptest.NewRecorder()
+		Handler()(w, httptest.NewRequest(http.MethodPut, "/log/level?level=debug", nil))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.True(t, strings.Contains(w.Body.String(), `"level":"info"`))
+	})
+
+	t.Run("Handler", func(t *testing.T) {
+		Set(LevelInfo, 0)
+		w := httptest.NewRecorder()
+		Handler()(w, httptest.NewRequest(http.MethodPut, "/log/level?level=debug&ttl=1m", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.Contains(w.Body.String(), `"level":"debug"`))
+
+		w = httptest.NewRecorder()
+		Handler()(w, httptest.NewRequest(http.MethodPut, "/log/level?level=trace", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = httptest.NewRecorder()
+		Handler()(w, httptest.NewRequest(http.MethodDelete, "/log/level", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+}
+
+func TestDebugToken(t *testing.T) {
+	defer SetDebugSecret("")
+
+	token := SignDebugToken("secret", time.Now().Add(time.Minute))
+	assert.False(t, VerifyDebugToken(token))
+
+	SetDebugSecret("secret")
+	assert.True(t, VerifyDebugToken(token))
+	assert.False(t, VerifyDebugToken(SignDebugToken("other", time.Now().Add(time.Minute))))
+	assert.False(t, VerifyDebugToken(SignDebugToken("secret", time.Now().Add(-time.Minute))))
+	assert.False(t, VerifyDebugToken("garbage"))
+}
@@ -0,0 +1,62 @@
+package loglevel
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DebugHeader forces debug logs of HTTP request, value is token created by SignDebugToken
+	DebugHeader = "X-Debug-Token"
+	// DebugMetadata forces debug logs of grpc call
+	DebugMetadata = "x-debug-token"
+)
+
+var (
+	secretMu sync.RWMutex
+	secret   []byte
+)
+
+// SetDebugSecret sets key of debug tokens, tokens are ignored without secret
+func SetDebugSecret(key string) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	secret = []byte(key)
+}
+
+// SignDebugToken creates token "<unix expiration>.<hex HMAC-SHA256 of expiration>"
+func SignDebugToken(key string, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expires + "." + sign([]byte(key), expires)
+}
+
+// VerifyDebugToken checks signature and expiration of token
+func VerifyDebugToken(token string) bool {
+	secretMu.RLock()
+	key := secret
+	secretMu.RUnlock()
+	if len(key) == 0 || token == "" {
+		return false
+	}
+
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	expires, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(parts[1]), []byte(sign(key, parts[0])))
+}
+
+func sign(key []byte, expires string) string {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
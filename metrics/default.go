@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Package level API of previous versions works with default registry, App sets its registry as default.
+
+var (
+	defaultMu         sync.Mutex
+	defaultRegistry   *Registry
+	pendingCollectors []prometheus.Collector
+
+	// Deprecated: use LastReq of App.Metrics()
+	LastReq prometheus.Gauge
+	// Deprecated: use CountError of App.Metrics()
+	CountError prometheus.Counter
+	// Deprecated: use CountRequest of App.Metrics()
+	CountRequest prometheus.Counter
+	// Deprecated: use ResponseTime of App.Metrics()
+	ResponseTime prometheus.Histogram
+)
+
+// SetDefault makes r registry of package functions and variables,
+// collectors added by AddCollector before are registered in r
+func SetDefault(r *Registry) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	setDefault(r)
+}
+
+func setDefault(r *Registry) {
+	defaultRegistry = r
+	LastReq, CountError, CountRequest, ResponseTime = r.LastReq, r.CountError, r.CountRequest, r.ResponseTime
+	collectors := pendingCollectors
+	pendingCollectors = nil
+	r.MustRegister(collectors...)
+}
+
+// AddBasicCollector creates default registry with prefix, registry of the same prefix is kept
+//
+// Deprecated: use NewRegistry or App.Metrics()
+func AddBasicCollector(prefix string) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultRegistry != nil && defaultRegistry.Prefix() == strings.ReplaceAll(prefix, "-", "_") {
+		return
+	}
+	setDefault(NewRegistry(prefix))
+}
+
+// AddCollector registers collectors in default registry, they are kept until default registry is set
+//
+// Deprecated: use Register of App.Metrics() or app.WithMetrics
+func AddCollector(collector ...prometheus.Collector) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultRegistry == nil {
+		pendingCollectors = append(pendingCollectors, collector...)
+		return
+	}
+	defaultRegistry.MustRegister(collector...)
+}
+
+// Metrics returns handler of default registry
+//
+// Deprecated: use Handler of App.Metrics()
+func Metrics() http.Handler {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultRegistry == nil {
+		// without basic collectors only added collectors are exposed
+		defaultRegistry = &Registry{reg: prometheus.NewPedanticRegistry(), collectors: make(map[prometheus.Collector]struct{})}
+		defaultRegistry.MustRegister(pendingCollectors...)
+		pendingCollectors = nil
+	}
+	return defaultRegistry.Handler()
+}
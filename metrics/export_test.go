@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPusher(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		body = string(data)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	r := NewRegistry("test")
+	r.CountRequest.Inc()
+	p := NewPusher(r, server.URL, "cron", 10*time.Millisecond, WithPushGrouping("instance", "host1"))
+	p.Start()
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(paths) > 0
+	}, time.Second, 5*time.Millisecond)
+	assert.Nil(t, p.Close())
+	assert.Nil(t, p.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "PUT /metrics/job/cron/instance/host1", paths[0])
+	assert.True(t, strings.Contains(body, "test_request_count"))
+
+	assert.NotNil(t, NewPusher(r, "http://127.0.0.1:1", "cron", 0).Push())
+}
+
+func TestStatsD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+	read := func() string {
+		buf := make([]byte, 64*1024)
+		assert.Nil(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		var packets []string
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				break
+			}
+			packets = append(packets, string(buf[:n]))
+			assert.Nil(t, conn.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+		}
+		return strings.Join(packets, "\n")
+	}
+
+	t.Run("StatsD", func(t *testing.T) {
+		r := NewRegistry("test")
+		s, err := NewStatsD(r, conn.LocalAddr().String(), 0, WithStatsDPrefix("svc."))
+		assert.Nil(t, err)
+
+		r.CountRequest.Add(3)
+		r.ObserveHTTP("/items", http.MethodGet, http.StatusOK, time.Millisecond, "")
+		assert.Nil(t, s.Flush())
+		lines := read()
+		assert.True(t, strings.Contains(lines, "svc.test_request_count:3|c"))
+		assert.True(t, strings.Contains(lines, "svc.test_http_requests_total.method_GET.route_/items.status_2xx:1|c"))
+		assert.True(t, strings.Contains(lines, "svc.test_last_request:0|g"))
+
+		r.CountRequest.Inc()
+		assert.Nil(t, s.Close())
+		lines = read()
+		assert.True(t, strings.Contains(lines, "svc.test_request_count:1|c"))
+		assert.False(t, strings.Contains(lines, "test_http_requests_total"))
+	})
+
+	t.Run("DogStatsD", func(t *testing.T) {
+		r := NewRegistry("test")
+		s, err := NewStatsD(r, conn.LocalAddr().String(), 0, WithDogStatsD())
+		assert.Nil(t, err)
+		defer s.Close()
+
+		r.ObserveGRPC("/items.Items/GetItem", "OK", time.Millisecond, "")
+		assert.Nil(t, s.Flush())
+		assert.True(t, strings.Contains(read(), "test_grpc_requests_total:1|c|#code:OK,method:/items.Items/GetItem"))
+	})
+}
@@ -1,60 +1,223 @@
 package metrics
 
 import (
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-var (
-	reg           = prometheus.NewPedanticRegistry()
-	collectorList = []prometheus.Collector{}
+// DefaultDurationBuckets are buckets of request duration histograms in seconds
+var DefaultDurationBuckets = prometheus.DefBuckets
 
-	LastReq      prometheus.Gauge
-	CountError   prometheus.Counter
-	CountRequest prometheus.Counter
-	ResponseTime prometheus.Histogram
-)
+// TraceIDLabel is label of exemplars with trace id
+const TraceIDLabel = "trace_id"
+
+// Registry keeps collectors of one App. Basic collectors are registered on creation,
+// the same collector registered twice is skipped, so Registry may be shared and reused safely.
+type Registry struct {
+	prefix string
+	reg    *prometheus.Registry
+
+	mu         sync.Mutex
+	collectors map[prometheus.Collector]struct{}
+
+	LastReq                prometheus.Gauge
+	CountError             prometheus.Counter
+	CountRequest           prometheus.Counter
+	ResponseTime           prometheus.Histogram
+	CountPanic             prometheus.Counter
+	CountConfigReloadError prometheus.Counter
+
+	// HTTPRequests and HTTPDuration are labeled by route pattern, method and status class (2xx, 4xx...)
+	HTTPRequests *prometheus.CounterVec
+	HTTPDuration *prometheus.HistogramVec
+	// GRPCRequests and GRPCDuration are labeled by full method and status code
+	GRPCRequests *prometheus.CounterVec
+	GRPCDuration *prometheus.HistogramVec
+}
 
-func AddBasicCollector(prefix string) {
+// NewRegistry creates registry with basic collectors, process and go runtime collectors,
+// names of basic collectors start with prefix (dashes are replaced with underscores)
+func NewRegistry(prefix string) *Registry {
 	prefix = strings.ReplaceAll(prefix, "-", "_")
-	LastReq = prometheus.NewGauge(prometheus.GaugeOpts{
+	r := &Registry{
+		prefix:     prefix,
+		reg:        prometheus.NewPedanticRegistry(),
+		collectors: make(map[prometheus.Collector]struct{}),
+	}
+
+	r.LastReq = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: prefix + "_last_request",
 		Help: "The time of last income request",
 	})
 
-	CountError = prometheus.NewCounter(prometheus.CounterOpts{
+	r.CountError = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: prefix + "_error_count",
 		Help: "The total number of request errors",
 	})
 
-	CountRequest = prometheus.NewCounter(prometheus.CounterOpts{
+	r.CountRequest = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: prefix + "_request_count",
 		Help: "The total request count",
 	})
 
-	ResponseTime = prometheus.NewHistogram(prometheus.HistogramOpts{
+	r.CountPanic = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: prefix + "_panic_count",
+		Help: "The total number of recovered panics",
+	})
+
+	r.CountConfigReloadError = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: prefix + "_config_reload_error_count",
+		Help: "The total number of rejected config reloads",
+	})
+
+	r.ResponseTime = prometheus.NewHistogram(prometheus.HistogramOpts{
 		Name:    prefix + "_response_time",
 		Help:    "Response time in ms",
 		Buckets: []float64{1, 10, 100, 1000, 10000},
 	})
 
-	collectorList = append(collectorList,
+	r.HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: prefix + "_http_requests_total",
+		Help: "The total number of HTTP requests",
+	}, []string{"route", "method", "status"})
+
+	r.GRPCRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: prefix + "_grpc_requests_total",
+		Help: "The total number of grpc requests",
+	}, []string{"method", "code"})
+
+	r.HTTPDuration, r.GRPCDuration = r.newDurations(DefaultDurationBuckets)
+
+	r.MustRegister(
 		prometheus.NewGoCollector(),
-		LastReq,
-		CountError,
-		CountRequest,
-		ResponseTime,
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		r.LastReq,
+		r.CountError,
+		r.CountRequest,
+		r.CountPanic,
+		r.CountConfigReloadError,
+		r.ResponseTime,
+		r.HTTPRequests,
+		r.HTTPDuration,
+		r.GRPCRequests,
+		r.GRPCDuration,
 	)
+	return r
+}
+
+func (r *Registry) newDurations(buckets []float64) (*prometheus.HistogramVec, *prometheus.HistogramVec) {
+	httpDuration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    r.prefix + "_http_request_duration_seconds",
+		Help:    "Duration of HTTP requests in seconds",
+		Buckets: buckets,
+	}, []string{"route", "method", "status"})
+	grpcDuration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    r.prefix + "_grpc_request_duration_seconds",
+		Help:    "Duration of grpc requests in seconds",
+		Buckets: buckets,
+	}, []string{"method", "code"})
+	return httpDuration, grpcDuration
+}
+
+// SetDurationBuckets replaces buckets of HTTP and grpc duration histograms, it should be called before serving
+func (r *Registry) SetDurationBuckets(buckets ...float64) error {
+	httpDuration, grpcDuration := r.newDurations(buckets)
+
+	r.mu.Lock()
+	r.reg.Unregister(r.HTTPDuration)
+	r.reg.Unregister(r.GRPCDuration)
+	delete(r.collectors, r.HTTPDuration)
+	delete(r.collectors, r.GRPCDuration)
+	r.HTTPDuration, r.GRPCDuration = httpDuration, grpcDuration
+	r.mu.Unlock()
+
+	return r.Register(httpDuration, grpcDuration)
+}
+
+// ObserveHTTP counts HTTP request, traceID (may be empty) is added to samples as exemplar
+func (r *Registry) ObserveHTTP(route, method string, status int, duration time.Duration, traceID string) {
+	class := strconv.Itoa(status/100) + "xx"
+	observe(r.HTTPRequests.WithLabelValues(route, method, class), r.HTTPDuration.WithLabelValues(route, method, class), duration, traceID)
+}
+
+// ObserveGRPC counts grpc request, traceID (may be empty) is added to samples as exemplar
+func (r *Registry) ObserveGRPC(method, code string, duration time.Duration, traceID string) {
+	observe(r.GRPCRequests.WithLabelValues(method, code), r.GRPCDuration.WithLabelValues(method, code), duration, traceID)
+}
+
+func observe(counter prometheus.Counter, histogram prometheus.Observer, duration time.Duration, traceID string) {
+	if traceID == "" {
+		counter.Inc()
+		histogram.Observe(duration.Seconds())
+		return
+	}
+	exemplar := prometheus.Labels{TraceIDLabel: traceID}
+	if adder, ok := counter.(prometheus.ExemplarAdder); ok {
+		adder.AddWithExemplar(1, exemplar)
+	} else {
+		counter.Inc()
+	}
+	if observer, ok := histogram.(prometheus.ExemplarObserver); ok {
+		observer.ObserveWithExemplar(duration.Seconds(), exemplar)
+	} else {
+		histogram.Observe(duration.Seconds())
+	}
+}
+
+// Prefix returns prefix of basic collectors
+func (r *Registry) Prefix() string {
+	return r.prefix
+}
+
+// Register registers collectors, already registered collectors are skipped.
+// Other collector with the same descriptors is rejected with prometheus.AlreadyRegisteredError
+func (r *Registry) Register(collector ...prometheus.Collector) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range collector {
+		if _, ok := r.collectors[c]; ok {
+			continue
+		}
+		if err := r.reg.Register(c); err != nil {
+			if are, ok := err.(prometheus.AlreadyRegisteredError); !ok || are.ExistingCollector != c {
+				return err
+			}
+		}
+		r.collectors[c] = struct{}{}
+	}
+	return nil
+}
+
+// MustRegister is Register which panics on error
+func (r *Registry) MustRegister(collector ...prometheus.Collector) {
+	if err := r.Register(collector...); err != nil {
+		panic(err)
+	}
+}
+
+// AddBuildInfo registers gauge <prefix>_build_info with value 1 and build labels
+func (r *Registry) AddBuildInfo(labels map[string]string) error {
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        r.prefix + "_build_info",
+		Help:        "Build information of app",
+		ConstLabels: labels,
+	})
+	buildInfo.Set(1)
+	return r.Register(buildInfo)
 }
 
-func AddCollector(collector ...prometheus.Collector) {
-	collectorList = append(collectorList, collector...)
+// Gatherer returns underlying registry for exporters
+func (r *Registry) Gatherer() prometheus.Gatherer {
+	return r.reg
 }
 
-// Metrics prometheus metrics
-func Metrics() http.Handler {
-	reg.MustRegister(collectorList...)
-	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+// Handler returns prometheus metrics handler, exemplars are exposed in OpenMetrics format when scraper accepts it
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.reg, promhttp.HandlerOpts{EnableOpenMetrics: true})
 }
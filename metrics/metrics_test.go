@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	first := NewRegistry("test-app")
+	second := NewRegistry("test-app")
+	assert.Equal(t, "test_app", first.Prefix())
+
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_app_custom_count", Help: "Custom"})
+	assert.Nil(t, first.Register(counter))
+	assert.Nil(t, first.Register(counter))
+	assert.Nil(t, second.Register(counter))
+	assert.Nil(t, first.AddBuildInfo(map[string]string{"version": "1.0"}))
+	assert.NotNil(t, first.Register(prometheus.NewCounter(prometheus.CounterOpts{Name: "test_app_custom_count", Help: "Other"})))
+
+	same := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_app_custom_count", Help: "Custom"})
+	err := first.Register(same)
+	if assert.IsType(t, prometheus.AlreadyRegisteredError{}, err) {
+		assert.Equal(t, counter, err.(prometheus.AlreadyRegisteredError).ExistingCollector)
+	}
+
+	first.CountRequest.Inc()
+	counter.Inc()
+	w := httptest.NewRecorder()
+	first.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	assert.True(t, strings.Contains(body, "test_app_request_count 1"))
+	assert.True(t, strings.Contains(body, "test_app_custom_count 1"))
+	assert.True(t, strings.Contains(body, `test_app_build_info{version="1.0"} 1`))
+	assert.True(t, strings.Contains(body, "go_goroutines"))
+
+	w = httptest.NewRecorder()
+	second.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.True(t, strings.Contains(w.Body.String(), "test_app_request_count 0"))
+}
+
+func TestDefault(t *testing.T) {
+	reset := func() {
+		defaultRegistry, pendingCollectors = nil, nil
+		LastReq, CountError, CountRequest, ResponseTime = nil, nil, nil, nil
+	}
+	defer reset()
+	scrape := func() string {
+		w := httptest.NewRecorder()
+		Metrics().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		return w.Body.String()
+	}
+
+	t.Run("BasicCollector", func(t *testing.T) {
+		reset()
+		custom := prometheus.NewCounter(prometheus.CounterOpts{Name: "default_app_custom_count", Help: "Custom"})
+		AddCollector(custom)
+		AddBasicCollector("default-app")
+		countRequest := CountRequest
+		AddBasicCollector("default_app")
+		assert.Equal(t, countRequest, CountRequest)
+
+		CountRequest.Inc()
+		custom.Inc()
+		body := scrape()
+		assert.True(t, strings.Contains(body, "default_app_request_count 1"))
+		assert.True(t, strings.Contains(body, "default_app_custom_count 1"))
+	})
+
+	t.Run("SetDefault", func(t *testing.T) {
+		reset()
+		r := NewRegistry("app")
+		SetDefault(r)
+		assert.Equal(t, r.CountError, CountError)
+		custom := prometheus.NewCounter(prometheus.CounterOpts{Name: "app_custom_count", Help: "Custom"})
+		AddCollector(custom)
+
+		w := httptest.NewRecorder()
+		r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		assert.True(t, strings.Contains(w.Body.String(), "app_custom_count 0"))
+	})
+
+	t.Run("OnlyCollectors", func(t *testing.T) {
+		reset()
+		AddCollector(prometheus.NewCounter(prometheus.CounterOpts{Name: "only_custom_count", Help: "Custom"}))
+		body := scrape()
+		assert.True(t, strings.Contains(body, "only_custom_count 0"))
+		assert.False(t, strings.Contains(body, "go_goroutines"))
+		assert.Nil(t, CountRequest)
+	})
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/push"
+	errors "github.com/sanches1984/gopkg-errors"
+	logger "github.com/sanches1984/gopkg-logger"
+)
+
+// Pusher pushes registry to Prometheus Pushgateway on interval and on Close,
+// it suits short-lived processes which exit before scrape
+type Pusher struct {
+	pusher   *push.Pusher
+	interval time.Duration
+
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+type PushOption func(p *Pusher)
+
+// WithPushGrouping adds grouping label to pushed metrics, e.g. instance
+func WithPushGrouping(name, value string) PushOption {
+	return func(p *Pusher) {
+		p.pusher = p.pusher.Grouping(name, value)
+	}
+}
+
+// WithPushBasicAuth sets credentials of Pushgateway
+func WithPushBasicAuth(user, password string) PushOption {
+	return func(p *Pusher) {
+		p.pusher = p.pusher.BasicAuth(user, password)
+	}
+}
+
+// NewPusher creates pusher of r to Pushgateway at url with job label, interval 0 disables periodic push
+func NewPusher(r *Registry, url, job string, interval time.Duration, opts ...PushOption) *Pusher {
+	p := &Pusher{
+		pusher:   push.New(url, job).Gatherer(r.Gatherer()),
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// Push replaces metrics of job on Pushgateway with current values
+func (p *Pusher) Push() error {
+	if err := p.pusher.Push(); err != nil {
+		return errors.Internal.ErrWrap(context.Background(), "metrics: push failed", err)
+	}
+	return nil
+}
+
+// Start pushes metrics on interval until Close
+func (p *Pusher) Start() {
+	if p.interval <= 0 {
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				if err := p.Push(); err != nil {
+					logger.Error(logger.App, "%v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops periodic push and pushes final values
+func (p *Pusher) Close() error {
+	var err error
+	p.once.Do(func() {
+		close(p.done)
+		p.wg.Wait()
+		err = p.Push()
+	})
+	return err
+}
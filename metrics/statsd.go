@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	errors "github.com/sanches1984/gopkg-errors"
+	logger "github.com/sanches1984/gopkg-logger"
+)
+
+// statsDPacketSize keeps datagrams below common MTU
+const statsDPacketSize = 1432
+
+// StatsD sends registry to StatsD over UDP on interval and on Close.
+// Gauges are sent as gauges, counters and count and sum of histograms and summaries as counters
+// with increase since previous flush. Labels are DogStatsD tags or, for plain StatsD, name suffixes "key_value".
+type StatsD struct {
+	registry  *Registry
+	conn      net.Conn
+	prefix    string
+	dogStatsD bool
+	interval  time.Duration
+
+	mu       sync.Mutex
+	previous map[string]float64
+
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+type StatsDOption func(s *StatsD)
+
+// WithDogStatsD sends labels as DogStatsD tags
+func WithDogStatsD() StatsDOption {
+	return func(s *StatsD) {
+		s.dogStatsD = true
+	}
+}
+
+// WithStatsDPrefix adds prefix to names of metrics, e.g. "billing."
+func WithStatsDPrefix(prefix string) StatsDOption {
+	return func(s *StatsD) {
+		s.prefix = prefix
+	}
+}
+
+// NewStatsD creates exporter of r to StatsD at addr (host:port), interval 0 disables periodic flush
+func NewStatsD(r *Registry, addr string, interval time.Duration, opts ...StatsDOption) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, errors.Internal.ErrWrap(context.Background(), "metrics: can't connect to statsd", err).
+			WithLogKV("addr", addr)
+	}
+	s := &StatsD{
+		registry: r,
+		conn:     conn,
+		interval: interval,
+		previous: make(map[string]float64),
+		done:     make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s, nil
+}
+
+// Flush sends current values
+func (s *StatsD) Flush() error {
+	families, err := s.registry.Gatherer().Gather()
+	if err != nil {
+		return errors.Internal.ErrWrap(context.Background(), "metrics: can't gather", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var packet bytes.Buffer
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			for _, line := range s.lines(family, m) {
+				if packet.Len() > 0 && packet.Len()+len(line)+1 > statsDPacketSize {
+					if err := s.send(packet.Bytes()); err != nil {
+						return err
+					}
+					packet.Reset()
+				}
+				if packet.Len() > 0 {
+					packet.WriteByte('\n')
+				}
+				packet.WriteString(line)
+			}
+		}
+	}
+	if packet.Len() > 0 {
+		return s.send(packet.Bytes())
+	}
+	return nil
+}
+
+func (s *StatsD) send(packet []byte) error {
+	if _, err := s.conn.Write(packet); err != nil {
+		return errors.Internal.ErrWrap(context.Background(), "metrics: can't send to statsd", err)
+	}
+	return nil
+}
+
+func (s *StatsD) lines(family *dto.MetricFamily, m *dto.Metric) []string {
+	name := family.GetName()
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		return s.counter(name, m.GetLabel(), m.GetCounter().GetValue())
+	case dto.MetricType_GAUGE:
+		return []string{s.line(name, m.GetLabel(), m.GetGauge().GetValue(), "g")}
+	case dto.MetricType_UNTYPED:
+		return []string{s.line(name, m.GetLabel(), m.GetUntyped().GetValue(), "g")}
+	case dto.MetricType_HISTOGRAM:
+		h := m.GetHistogram()
+		return append(s.counter(name+"_count", m.GetLabel(), float64(h.GetSampleCount())),
+			s.counter(name+"_sum", m.GetLabel(), h.GetSampleSum())...)
+	case dto.MetricType_SUMMARY:
+		summary := m.GetSummary()
+		return append(s.counter(name+"_count", m.GetLabel(), float64(summary.GetSampleCount())),
+			s.counter(name+"_sum", m.GetLabel(), summary.GetSampleSum())...)
+	}
+	return nil
+}
+
+// counter converts cumulative value to increase since previous flush, nothing is sent without increase
+func (s *StatsD) counter(name string, labels []*dto.LabelPair, value float64) []string {
+	key := s.line(name, labels, 0, "")
+	delta := value - s.previous[key]
+	s.previous[key] = value
+	if delta <= 0 {
+		return nil
+	}
+	return []string{s.line(name, labels, delta, "c")}
+}
+
+func (s *StatsD) line(name string, labels []*dto.LabelPair, value float64, typ string) string {
+	sorted := append([]*dto.LabelPair{}, labels...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetName() < sorted[j].GetName() })
+
+	var b strings.Builder
+	b.WriteString(statsDName(s.prefix + name))
+	if !s.dogStatsD {
+		for _, label := range sorted {
+			b.WriteString("." + statsDName(label.GetName()+"_"+label.GetValue()))
+		}
+	}
+	b.WriteString(":" + strconv.FormatFloat(value, 'f', -1, 64) + "|" + typ)
+	if s.dogStatsD && len(sorted) > 0 {
+		tags := make([]string, 0, len(sorted))
+		for _, label := range sorted {
+			tags = append(tags, statsDName(label.GetName())+":"+statsDTag(label.GetValue()))
+		}
+		b.WriteString("|#" + strings.Join(tags, ","))
+	}
+	return b.String()
+}
+
+var statsDReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", " ", "_", "\n", "_")
+
+func statsDName(s string) string {
+	return statsDReplacer.Replace(s)
+}
+
+// statsDTag keeps ':' which is allowed in tag values
+func statsDTag(s string) string {
+	return strings.NewReplacer("|", "_", "#", "_", ",", "_", "\n", "_").Replace(s)
+}
+
+// Start flushes metrics on interval until Close
+func (s *StatsD) Start() {
+	if s.interval <= 0 {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				if err := s.Flush(); err != nil {
+					logger.Error(logger.App, "%v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops periodic flush, sends final values and closes connection
+func (s *StatsD) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		err = s.Flush()
+		if closeErr := s.conn.Close(); err == nil && closeErr != nil {
+			err = closeErr
+		}
+	})
+	return err
+}
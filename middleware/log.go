@@ -3,11 +3,14 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/sanches1984/gopkg-app/client/sentry"
+	"github.com/sanches1984/gopkg-app/loglevel"
 	"github.com/sanches1984/gopkg-app/metrics"
 	errors "github.com/sanches1984/gopkg-errors"
 	"github.com/sanches1984/gopkg-logger"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 	"net/http"
 	"strconv"
 	"time"
@@ -17,6 +20,12 @@ const loggerLevel = 500
 
 var loggerHttpRegisterKey = new(struct{})
 
+// logInfo and logError are replaced in tests
+var (
+	logInfo  = logger.Info
+	logError = logger.Error
+)
+
 type loggedResponseWriter struct {
 	http.ResponseWriter
 	status int
@@ -35,23 +44,38 @@ func (v *loggedResponseWriter) Write(bytes []byte) (int, error) {
 	return v.ResponseWriter.Write(bytes)
 }
 
+// NewLogMiddleware logs requests
 func NewLogMiddleware() func(next http.Handler) http.Handler {
+	return NewLogMiddlewareWithMetrics(nil)
+}
+
+// NewLogMiddlewareWithMetrics logs requests and counts them in m, m may be nil
+func NewLogMiddlewareWithMetrics(m *metrics.Registry) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			metrics.LastReq.SetToCurrentTime()
-			metrics.CountRequest.Inc()
+			if m != nil {
+				m.LastReq.SetToCurrentTime()
+				m.CountRequest.Inc()
+			}
 
 			start := time.Now().UnixNano()
 
 			lr := &loggedResponseWriter{ResponseWriter: w, status: http.StatusOK}
-			r = r.WithContext(context.WithValue(r.Context(), &loggerHttpRegisterKey, true))
+			ctx := context.WithValue(r.Context(), &loggerHttpRegisterKey, true)
+			if loglevel.VerifyDebugToken(r.Header.Get(loglevel.DebugHeader)) {
+				ctx = loglevel.WithForcedDebug(ctx)
+			}
+			r = r.WithContext(ctx)
+			loglevel.Debug(ctx, "%v %s %s started", r.RemoteAddr, r.Method, r.URL)
 			next.ServeHTTP(lr, r)
 
 			reqDurationMs := (time.Now().UnixNano() - start) / int64(time.Millisecond)
-			metrics.ResponseTime.Observe(float64(reqDurationMs))
+			if m != nil {
+				m.ResponseTime.Observe(float64(reqDurationMs))
+			}
 
 			if lr.status >= loggerLevel {
-				logger.Error(r.Context(), lr.error)
+				logError(r.Context(), lr.error)
 				sentry.Error(
 					errors.Internal.Err(r.Context(), lr.error),
 					"http.remote_addr", r.RemoteAddr,
@@ -62,34 +86,34 @@ func NewLogMiddleware() func(next http.Handler) http.Handler {
 				)
 			}
 
-			logger.Info(r.Context(), "%v %d %s %s %dms", r.RemoteAddr, lr.status, r.Method, r.URL, reqDurationMs)
+			if loglevel.Enabled(ctx, loglevel.LevelInfo) {
+				logInfo(ctx, "%v %d %s %s %dms", r.RemoteAddr, lr.status, r.Method, r.URL, reqDurationMs)
+			}
 		})
 	}
 }
 
 func NewLogInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-		msgFormat := "method: %s, request: %s"
-		str, _ := json.Marshal(req)
-		if len(str) > 500 {
-			str = str[:500]
-			msgFormat += " ..."
-		}
-		msgParam := []interface{}{info.FullMethod, str}
-		kvList := logExtraFromContext(ctx)
-		if len(kvList)&1 == 1 {
-			kvList = append(kvList, "?")
-		}
-		for i := 0; i < len(kvList); i += 2 {
-			msgFormat = "%s: %v, " + msgFormat
-			msgParam = append([]interface{}{kvList[i], kvList[i+1]}, msgParam...)
+		ctx = withDebugMetadata(ctx)
+		if loglevel.Enabled(ctx, loglevel.LevelInfo) {
+			msgFormat := "method: %s, request: %s"
+			str, _ := json.Marshal(req)
+			// debug logs contain whole request
+			if len(str) > 500 && !loglevel.Enabled(ctx, loglevel.LevelDebug) {
+				str = str[:500]
+				msgFormat += " ..."
+			}
+			msgFormat, msgParam := withLogExtra(ctx, msgFormat, []interface{}{info.FullMethod, str})
+			logInfo(ctx, msgFormat, msgParam...)
 		}
-		logger.Info(ctx, msgFormat, msgParam...)
 
 		resp, err = handler(ctx, req)
 
-		//str, _ = json.Marshal(resp)
-		//logger.Info(ctx, "Response: %s", str)
+		if loglevel.Enabled(ctx, loglevel.LevelDebug) {
+			str, _ := json.Marshal(resp)
+			loglevel.Debug(ctx, "method: %s, response: %s, error: %v", info.FullMethod, str, err)
+		}
 
 		tagKV := []string{
 			"request_id", GetRequestId(ctx),
@@ -107,3 +131,59 @@ func NewLogInterceptor() grpc.UnaryServerInterceptor {
 		return resp, err
 	}
 }
+
+func NewLogStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withDebugMetadata(ss.Context())
+		if loglevel.Enabled(ctx, loglevel.LevelInfo) {
+			msgFormat, msgParam := withLogExtra(ctx, "stream method: %s, client stream: %t, server stream: %t",
+				[]interface{}{info.FullMethod, info.IsClientStream, info.IsServerStream})
+			logInfo(ctx, msgFormat, msgParam...)
+		}
+
+		start := time.Now()
+		wrapped := grpc_middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ctx
+		err := handler(srv, wrapped)
+
+		tagKV := []string{
+			"request_id", GetRequestId(ctx),
+			"grpc.method", info.FullMethod,
+		}
+		sentry.Error(err, tagKV...)
+		if err != nil {
+			logError(ctx, "stream method: %s, error: %v, %dms", info.FullMethod, err, time.Since(start).Milliseconds())
+		} else if loglevel.Enabled(ctx, loglevel.LevelInfo) {
+			logInfo(ctx, "stream method: %s finished, %dms", info.FullMethod, time.Since(start).Milliseconds())
+		}
+
+		return err
+	}
+}
+
+// withDebugMetadata forces debug logs of call with valid token in grpc metadata
+func withDebugMetadata(ctx context.Context) context.Context {
+	if loglevel.Enabled(ctx, loglevel.LevelDebug) {
+		return ctx
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, token := range md.Get(loglevel.DebugMetadata) {
+		if loglevel.VerifyDebugToken(token) {
+			return loglevel.WithForcedDebug(ctx)
+		}
+	}
+	return ctx
+}
+
+// withLogExtra prepends key/value pairs from LogExtraToContext to log message
+func withLogExtra(ctx context.Context, msgFormat string, msgParam []interface{}) (string, []interface{}) {
+	kvList := logExtraFromContext(ctx)
+	if len(kvList)&1 == 1 {
+		kvList = append(kvList, "?")
+	}
+	for i := 0; i < len(kvList); i += 2 {
+		msgFormat = "%s: %v, " + msgFormat
+		msgParam = append([]interface{}{kvList[i], kvList[i+1]}, msgParam...)
+	}
+	return msgFormat, msgParam
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sanches1984/gopkg-app/loglevel"
+	logger "github.com/sanches1984/gopkg-logger"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestLogLevel(t *testing.T) {
+	var logs []string
+	logInfo = func(ctx context.Context, format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+	defer func() {
+		logInfo = logger.Info
+		loglevel.Set(loglevel.LevelInfo, 0)
+		loglevel.SetDebugSecret("")
+	}()
+	loglevel.SetDebugSecret("secret")
+	token := loglevel.SignDebugToken("secret", time.Now().Add(time.Minute))
+
+	interceptor := NewLogInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/items.Items/GetItem"}
+	call := func(ctx context.Context) int {
+		logs = nil
+		_, _ = interceptor(ctx, "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "resp", nil
+		})
+		return len(logs)
+	}
+	handler := NewLogMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(header string) int {
+		logs = nil
+		r := httptest.NewRequest(http.MethodGet, "/items", nil)
+		if header != "" {
+			r.Header.Set(loglevel.DebugHeader, header)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		return len(logs)
+	}
+
+	t.Run("Info", func(t *testing.T) {
+		loglevel.Set(loglevel.LevelInfo, 0)
+		assert.Equal(t, 1, call(context.Background()))
+		assert.Equal(t, 1, serve(""))
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		loglevel.Set(loglevel.LevelError, 0)
+		assert.Equal(t, 0, call(context.Background()))
+		assert.Equal(t, 0, serve(""))
+	})
+
+	t.Run("ForcedDebug", func(t *testing.T) {
+		loglevel.Set(loglevel.LevelError, 0)
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(loglevel.DebugMetadata, token))
+		assert.Equal(t, 1, call(ctx))
+		assert.Equal(t, 1, serve(token))
+		assert.Equal(t, 0, serve("garbage"))
+	})
+}
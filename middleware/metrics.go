@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/sanches1984/gopkg-app/metrics"
+	"github.com/sanches1984/gopkg-app/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// unknownRoute is route label of requests not matched by router, it keeps cardinality of label bounded
+const unknownRoute = "unknown"
+
+var traceIDKey = new(struct{})
+
+// traceIDHolder receives trace id from tracing interceptor which runs inside of metrics one
+type traceIDHolder struct {
+	mu sync.Mutex
+	id string
+}
+
+// RecordTraceID saves trace id of span from ctx for RED metrics exemplars,
+// tracing middlewares running after metrics middleware should call it. The first recorded trace id is kept
+func RecordTraceID(ctx context.Context) {
+	holder, ok := ctx.Value(&traceIDKey).(*traceIDHolder)
+	if !ok {
+		return
+	}
+	if id := tracing.TraceID(ctx); id != "" {
+		holder.mu.Lock()
+		if holder.id == "" {
+			holder.id = id
+		}
+		holder.mu.Unlock()
+	}
+}
+
+// withTraceIDHolder reuses holder of ctx: generated HTTP handlers run unary interceptors
+// inside of HTTP middleware, so trace id recorded by any of them is used by both metrics
+func withTraceIDHolder(ctx context.Context) (context.Context, *traceIDHolder) {
+	if holder, ok := ctx.Value(&traceIDKey).(*traceIDHolder); ok {
+		RecordTraceID(ctx)
+		return ctx, holder
+	}
+	holder := &traceIDHolder{id: tracing.TraceID(ctx)}
+	return context.WithValue(ctx, &traceIDKey, holder), holder
+}
+
+func (h *traceIDHolder) get() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.id
+}
+
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (v *statusResponseWriter) WriteHeader(code int) {
+	v.status = code
+	v.ResponseWriter.WriteHeader(code)
+}
+
+// NewMetricsMiddleware counts requests and their duration by chi route pattern, method and status class
+func NewMetricsMiddleware(m *metrics.Registry) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx, holder := withTraceIDHolder(r.Context())
+			sw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			route := unknownRoute
+			if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			m.ObserveHTTP(route, r.Method, sw.status, time.Since(start), holder.get())
+		})
+	}
+}
+
+// NewMetricsInterceptor counts requests and their duration by method and status code,
+// it should be placed before error converters to see converted codes
+func NewMetricsInterceptor(m *metrics.Registry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx, holder := withTraceIDHolder(ctx)
+		resp, err := handler(ctx, req)
+		m.ObserveGRPC(info.FullMethod, status.Code(err).String(), time.Since(start), holder.get())
+		return resp, err
+	}
+}
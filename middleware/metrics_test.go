@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sanches1984/gopkg-app/metrics"
+	"github.com/sanches1984/gopkg-app/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-client-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	m := metrics.NewRegistry("test")
+	tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewNullReporter())
+	defer closer.Close()
+
+	exemplar := func(m *metrics.Registry, name string) string {
+		families, err := m.Gatherer().Gather()
+		assert.Nil(t, err)
+		for _, family := range families {
+			if family.GetName() != name {
+				continue
+			}
+			for _, label := range family.GetMetric()[0].GetCounter().GetExemplar().GetLabel() {
+				if label.GetName() == metrics.TraceIDLabel {
+					return label.GetValue()
+				}
+			}
+		}
+		return ""
+	}
+
+	t.Run("HTTP", func(t *testing.T) {
+		router := chi.NewRouter()
+		router.Use(NewMetricsMiddleware(m))
+		router.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/1", nil))
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/2", nil))
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/other", nil))
+
+		assert.Equal(t, float64(2), testutil.ToFloat64(m.HTTPRequests.WithLabelValues("/items/{id}", http.MethodGet, "4xx")))
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.HTTPRequests.WithLabelValues(unknownRoute, http.MethodGet, "4xx")))
+	})
+
+	t.Run("GRPC", func(t *testing.T) {
+		var traceID string
+		interceptor := NewMetricsInterceptor(m)
+		info := &grpc.UnaryServerInfo{FullMethod: "/items.Items/GetItem"}
+		_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			span := tracer.StartSpan("GetItem")
+			defer span.Finish()
+			ctx = opentracing.ContextWithSpan(ctx, span)
+			traceID = span.Context().(jaeger.SpanContext).TraceID().String()
+			RecordTraceID(ctx)
+			return nil, status.Error(codes.NotFound, "not found")
+		})
+		assert.NotNil(t, err)
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.GRPCRequests.WithLabelValues(info.FullMethod, codes.NotFound.String())))
+		assert.NotEmpty(t, traceID)
+		assert.Equal(t, traceID, exemplar(m, "test_grpc_requests_total"))
+	})
+
+	t.Run("HTTPExemplar", func(t *testing.T) {
+		m := metrics.NewRegistry("exemplar")
+		var traceID, innerTraceID string
+		router := chi.NewRouter()
+		router.Use(NewMetricsMiddleware(m), NewTracingMiddleware(tracer))
+		router.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+			traceID = tracing.TraceID(r.Context())
+			// generated handlers run unary chain with metrics and tracing interceptors
+			interceptor := NewMetricsInterceptor(m)
+			info := &grpc.UnaryServerInfo{FullMethod: "/items.Items/GetItem"}
+			_, _ = interceptor(r.Context(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				span := tracer.StartSpan("GetItem")
+				defer span.Finish()
+				ctx = opentracing.ContextWithSpan(ctx, span)
+				innerTraceID = tracing.TraceID(ctx)
+				RecordTraceID(ctx)
+				return nil, nil
+			})
+		})
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/1", nil))
+		assert.NotEmpty(t, traceID)
+		assert.NotEqual(t, traceID, innerTraceID)
+		assert.Equal(t, traceID, exemplar(m, "exemplar_http_requests_total"))
+		assert.Equal(t, traceID, exemplar(m, "exemplar_grpc_requests_total"))
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.HTTPRequests.WithLabelValues("/items/{id}", http.MethodGet, "2xx")))
+	})
+
+	t.Run("Buckets", func(t *testing.T) {
+		assert.Nil(t, m.SetDurationBuckets(0.01, 0.1, 1))
+		m.ObserveHTTP("/", http.MethodGet, http.StatusOK, 0, "")
+		assert.Equal(t, 1, testutil.CollectAndCount(m.HTTPDuration))
+	})
+}
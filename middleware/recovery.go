@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/sanches1984/gopkg-app/client/sentry"
+	"github.com/sanches1984/gopkg-app/metrics"
+	errors "github.com/sanches1984/gopkg-errors"
+	errtransport "github.com/sanches1984/gopkg-errors/transport"
+	logger "github.com/sanches1984/gopkg-logger"
+)
+
+// NewRecoveryMiddleware recovers panics of next handlers: panic is logged, reported to sentry
+// and rendered as 500 ErrorResponse. It should be placed after request id middleware to tag reports
+func NewRecoveryMiddleware() func(next http.Handler) http.Handler {
+	return NewRecoveryMiddlewareWithMetrics(nil)
+}
+
+// NewRecoveryMiddlewareWithMetrics works like NewRecoveryMiddleware and counts panics in m, m may be nil
+func NewRecoveryMiddlewareWithMetrics(m *metrics.Registry) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				data := recover()
+				if data == nil {
+					return
+				}
+				// http.ErrAbortHandler aborts response intentionally, net/http handles it silently
+				if data == http.ErrAbortHandler {
+					panic(data)
+				}
+
+				if m != nil {
+					m.CountPanic.Inc()
+				}
+				logger.Error(r.Context(), "http: panic %s %s: %v\n%s", r.Method, r.URL, data, debug.Stack())
+				sentry.Panic(data,
+					"request_id", GetRequestId(r.Context()),
+					"http.method", r.Method,
+					"http.url", r.URL.String(),
+				)
+				errtransport.ErrorRenderer(r.Context(), r, w,
+					errors.Internal.Err(r.Context(), "Internal server error").WithLogKV("panic", fmt.Sprint(data)))
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sanches1984/gopkg-app/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	m := metrics.NewRegistry("test")
+
+	t.Run("Panic", func(t *testing.T) {
+		handler := NewRecoveryMiddlewareWithMetrics(m)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))
+		assert.NotPanics(t, func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.CountPanic))
+	})
+
+	t.Run("WithoutMetrics", func(t *testing.T) {
+		handler := NewRecoveryMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))
+		assert.NotPanics(t, func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	})
+
+	t.Run("AbortHandler", func(t *testing.T) {
+		handler := NewRecoveryMiddlewareWithMetrics(m)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+		assert.Panics(t, func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.CountPanic))
+	})
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"context"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+)
+
+// NewStreamFromUnaryInterceptor runs unary interceptor around stream handler, request is always nil.
+// It suits interceptors which work with context and returned error only, for example error converters.
+func NewStreamFromUnaryInterceptor(interceptor grpc.UnaryServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		unaryInfo := &grpc.UnaryServerInfo{Server: srv, FullMethod: info.FullMethod}
+		_, err := interceptor(ss.Context(), nil, unaryInfo, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			wrapped := grpc_middleware.WrapServerStream(ss)
+			wrapped.WrappedContext = ctx
+			return nil, handler(srv, wrapped)
+		})
+		return err
+	}
+}
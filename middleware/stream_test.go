@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	pkgvalidator "github.com/sanches1984/gopkg-app/validator"
+	validatormw "github.com/sanches1984/gopkg-app/validator/middleware"
+	logger "github.com/sanches1984/gopkg-logger"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testStreamMessage struct {
+	Name string `validate:"required"`
+}
+
+// testServerStream receives messages from list and keeps sent ones
+type testServerStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	messages []testStreamMessage
+	sent     []interface{}
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testServerStream) RecvMsg(m interface{}) error {
+	if len(s.messages) == 0 {
+		return io.EOF
+	}
+	*m.(*testStreamMessage) = s.messages[0]
+	s.messages = s.messages[1:]
+	return nil
+}
+
+func (s *testServerStream) SendMsg(m interface{}) error {
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+// testConvertErrors converts validation errors to InvalidArgument and other errors to Internal
+func testConvertErrors(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err == nil {
+		return resp, nil
+	}
+	if _, ok := err.(validator.ValidationErrors); ok {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, ok := status.FromError(err); ok {
+		return nil, err
+	}
+	return nil, status.Error(codes.Internal, err.Error())
+}
+
+func TestStreamChain(t *testing.T) {
+	var mu sync.Mutex
+	var logs []string
+	logInfo = func(ctx context.Context, format string, args ...interface{}) {
+		mu.Lock()
+		logs = append(logs, fmt.Sprintf(format, args...))
+		mu.Unlock()
+	}
+	logError = logInfo
+	defer func() {
+		logInfo, logError = logger.Info, logger.Error
+	}()
+
+	chain := grpc_middleware.ChainStreamServer(
+		NewStreamFromUnaryInterceptor(testConvertErrors),
+		validatormw.NewValidateStreamServerInterceptor(pkgvalidator.New()),
+		NewLogStreamInterceptor(),
+	)
+	info := &grpc.StreamServerInfo{FullMethod: "/items.Items/Watch", IsClientStream: true, IsServerStream: true}
+	echo := func(srv interface{}, ss grpc.ServerStream) error {
+		for {
+			var m testStreamMessage
+			if err := ss.RecvMsg(&m); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+			if m.Name == "fail" {
+				return fmt.Errorf("can't watch %s", m.Name)
+			}
+			if err := ss.SendMsg(&m); err != nil {
+				return err
+			}
+		}
+	}
+	run := func(messages ...testStreamMessage) (*testServerStream, error) {
+		mu.Lock()
+		logs = nil
+		mu.Unlock()
+		ss := &testServerStream{ctx: context.Background(), messages: messages}
+		return ss, chain(nil, ss, info, echo)
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		ss, err := run(testStreamMessage{Name: "a"}, testStreamMessage{Name: "b"})
+		assert.Nil(t, err)
+		assert.Len(t, ss.sent, 2)
+		mu.Lock()
+		defer mu.Unlock()
+		if assert.Len(t, logs, 2) {
+			assert.Equal(t, "stream method: /items.Items/Watch, client stream: true, server stream: true", logs[0])
+			assert.Contains(t, logs[1], "stream method: /items.Items/Watch finished")
+		}
+	})
+
+	t.Run("InvalidFirstMessage", func(t *testing.T) {
+		ss, err := run(testStreamMessage{}, testStreamMessage{Name: "b"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Empty(t, ss.sent)
+	})
+
+	t.Run("OnlyFirstMessageValidated", func(t *testing.T) {
+		ss, err := run(testStreamMessage{Name: "a"}, testStreamMessage{})
+		assert.Nil(t, err)
+		assert.Len(t, ss.sent, 2)
+	})
+
+	t.Run("HandlerError", func(t *testing.T) {
+		ss, err := run(testStreamMessage{Name: "a"}, testStreamMessage{Name: "fail"})
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Len(t, ss.sent, 1)
+		mu.Lock()
+		defer mu.Unlock()
+		if assert.Len(t, logs, 2) {
+			assert.Contains(t, logs[1], "stream method: /items.Items/Watch, error: can't watch fail")
+		}
+	})
+}
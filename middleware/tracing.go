@@ -1,11 +1,61 @@
 package middleware
 
 import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
 	"github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
 	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
 	"google.golang.org/grpc"
 )
 
+// NewTracingMiddleware starts server span continuing trace from request headers, its trace id is recorded
+// for metrics exemplars. It should be placed after metrics middleware
+func NewTracingMiddleware(tracer opentracing.Tracer) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parent, _ := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+			span := tracer.StartSpan("HTTP "+r.Method, ext.RPCServerOption(parent))
+			defer span.Finish()
+			ext.HTTPMethod.Set(span, r.Method)
+			ext.HTTPUrl.Set(span, r.URL.String())
+
+			ctx := opentracing.ContextWithSpan(r.Context(), span)
+			RecordTraceID(ctx)
+			sw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+				span.SetOperationName("HTTP " + r.Method + " " + rctx.RoutePattern())
+			}
+			ext.HTTPStatusCode.Set(span, uint16(sw.status))
+			if sw.status >= http.StatusInternalServerError {
+				ext.Error.Set(span, true)
+			}
+		})
+	}
+}
+
+// NewUnaryTracingInterceptor starts server span, its trace id is recorded for metrics exemplars
 func NewUnaryTracingInterceptor(tracer opentracing.Tracer) grpc.UnaryServerInterceptor {
-	return grpc_opentracing.UnaryServerInterceptor(grpc_opentracing.WithTracer(tracer))
+	interceptor := grpc_opentracing.UnaryServerInterceptor(grpc_opentracing.WithTracer(tracer))
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			RecordTraceID(ctx)
+			return handler(ctx, req)
+		})
+	}
+}
+
+// NewStreamTracingInterceptor starts server span, its trace id is recorded for metrics exemplars
+func NewStreamTracingInterceptor(tracer opentracing.Tracer) grpc.StreamServerInterceptor {
+	interceptor := grpc_opentracing.StreamServerInterceptor(grpc_opentracing.WithTracer(tracer))
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return interceptor(srv, ss, info, func(srv interface{}, stream grpc.ServerStream) error {
+			RecordTraceID(stream.Context())
+			return handler(srv, stream)
+		})
+	}
 }
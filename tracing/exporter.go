@@ -0,0 +1,154 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	errors "github.com/sanches1984/gopkg-errors"
+	"github.com/uber/jaeger-client-go"
+	"google.golang.org/grpc"
+)
+
+// Exporter is tracing backend which receives finished spans: JaegerUDP, OTLPGRPC or OTLPHTTP
+type Exporter interface {
+	reporter(serviceName string) (jaeger.Reporter, error)
+}
+
+type jaegerUDP string
+
+// JaegerUDP sends spans to jaeger agent by UDP (host:port)
+func JaegerUDP(addr string) Exporter {
+	return jaegerUDP(addr)
+}
+
+func (addr jaegerUDP) reporter(string) (jaeger.Reporter, error) {
+	transport, err := jaeger.NewUDPTransport(string(addr), 0)
+	if err != nil {
+		return nil, errors.Internal.ErrWrap(context.Background(), "tracing: can't create jaeger transport", err).
+			WithLogKV("addr", string(addr))
+	}
+	return jaeger.NewRemoteReporter(transport), nil
+}
+
+// Defaults of OTLP exporters
+const (
+	DefaultOTLPBatchSize     = 512
+	DefaultOTLPBatchInterval = 5 * time.Second
+	DefaultOTLPQueueSize     = 2048
+	DefaultOTLPTimeout       = 10 * time.Second
+)
+
+type otlpOptions struct {
+	headers       map[string]string
+	batchSize     int
+	batchInterval time.Duration
+	queueSize     int
+	timeout       time.Duration
+	dialOptions   []grpc.DialOption
+}
+
+type OTLPOption func(o *otlpOptions)
+
+// WithOTLPHeaders adds headers (grpc metadata) to export requests, e.g. authorization of collector
+func WithOTLPHeaders(headers map[string]string) OTLPOption {
+	return func(o *otlpOptions) {
+		for k, v := range headers {
+			o.headers[k] = v
+		}
+	}
+}
+
+// WithOTLPBatch sets max number of spans in one request and max delay of span export
+func WithOTLPBatch(size int, interval time.Duration) OTLPOption {
+	return func(o *otlpOptions) {
+		o.batchSize = size
+		o.batchInterval = interval
+	}
+}
+
+// WithOTLPQueueSize sets number of spans waiting for export, new spans are dropped when queue is full
+func WithOTLPQueueSize(size int) OTLPOption {
+	return func(o *otlpOptions) {
+		o.queueSize = size
+	}
+}
+
+// WithOTLPTimeout sets timeout of export request
+func WithOTLPTimeout(timeout time.Duration) OTLPOption {
+	return func(o *otlpOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithOTLPDialOption sets options of grpc connection to collector, by default connection is insecure
+func WithOTLPDialOption(opts ...grpc.DialOption) OTLPOption {
+	return func(o *otlpOptions) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// newOTLPOptions rejects non-positive batch, queue and timeout settings, reporter can't run with them
+func newOTLPOptions(opts []OTLPOption) (*otlpOptions, error) {
+	o := &otlpOptions{
+		headers:       make(map[string]string),
+		batchSize:     DefaultOTLPBatchSize,
+		batchInterval: DefaultOTLPBatchInterval,
+		queueSize:     DefaultOTLPQueueSize,
+		timeout:       DefaultOTLPTimeout,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.batchSize <= 0 || o.batchInterval <= 0 || o.queueSize <= 0 || o.timeout <= 0 {
+		return nil, errors.Internal.Err(context.Background(), "tracing: invalid OTLP options").
+			WithLogKV("batchSize", o.batchSize, "batchInterval", o.batchInterval.String(),
+				"queueSize", o.queueSize, "timeout", o.timeout.String())
+	}
+	return o, nil
+}
+
+type otlpHTTP struct {
+	url  string
+	opts []OTLPOption
+}
+
+// OTLPHTTP sends spans to OpenTelemetry collector by OTLP/HTTP with protobuf encoding,
+// url is full address of traces endpoint, e.g. "http://otel-collector:4318/v1/traces"
+func OTLPHTTP(url string, opts ...OTLPOption) Exporter {
+	return otlpHTTP{url: url, opts: opts}
+}
+
+func (e otlpHTTP) reporter(serviceName string) (jaeger.Reporter, error) {
+	o, err := newOTLPOptions(e.opts)
+	if err != nil {
+		return nil, err
+	}
+	return newOTLPReporter(serviceName, o, newHTTPSender(e.url, o)), nil
+}
+
+type otlpGRPC struct {
+	addr string
+	opts []OTLPOption
+}
+
+// OTLPGRPC sends spans to OpenTelemetry collector by OTLP/gRPC, addr is host:port, e.g. "otel-collector:4317"
+func OTLPGRPC(addr string, opts ...OTLPOption) Exporter {
+	return otlpGRPC{addr: addr, opts: opts}
+}
+
+func (e otlpGRPC) reporter(serviceName string) (jaeger.Reporter, error) {
+	o, err := newOTLPOptions(e.opts)
+	if err != nil {
+		return nil, err
+	}
+	dialOptions := o.dialOptions
+	if len(dialOptions) == 0 {
+		dialOptions = []grpc.DialOption{grpc.WithInsecure()}
+	}
+	conn, err := grpc.Dial(e.addr, dialOptions...)
+	if err != nil {
+		return nil, errors.Internal.ErrWrap(context.Background(), "tracing: can't connect to OTLP collector", err).
+			WithLogKV("addr", e.addr)
+	}
+	return newOTLPReporter(serviceName, o, newGRPCSender(conn, o)), nil
+}
@@ -0,0 +1,357 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	errors "github.com/sanches1984/gopkg-errors"
+	logger "github.com/sanches1984/gopkg-logger"
+	"github.com/uber/jaeger-client-go"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpScopeName is name of instrumentation library of exported spans
+const otlpScopeName = "github.com/sanches1984/gopkg-app/tracing"
+
+type otlpSender interface {
+	send(ctx context.Context, request *coltracepb.ExportTraceServiceRequest) error
+	close() error
+}
+
+// otlpSpan is copy of finished jaeger span, reporter doesn't keep span itself
+type otlpSpan struct {
+	context jaeger.SpanContext
+	name    string
+	start   time.Time
+	end     time.Time
+	tags    opentracing.Tags
+	logs    []opentracing.LogRecord
+	links   []jaeger.SpanContext
+}
+
+// otlpReporter implements jaeger.Reporter, spans are exported in batches by background goroutine
+type otlpReporter struct {
+	// dropped counts spans dropped on full queue since the last report, it is first for atomic alignment
+	dropped uint64
+
+	serviceName string
+	opts        *otlpOptions
+	sender      otlpSender
+
+	queue chan otlpSpan
+	done  chan struct{}
+	wg    sync.WaitGroup
+	once  sync.Once
+}
+
+func newOTLPReporter(serviceName string, opts *otlpOptions, sender otlpSender) *otlpReporter {
+	r := &otlpReporter{
+		serviceName: serviceName,
+		opts:        opts,
+		sender:      sender,
+		queue:       make(chan otlpSpan, opts.queueSize),
+		done:        make(chan struct{}),
+	}
+	r.wg.Add(1)
+	go r.run()
+	return r
+}
+
+// Report queues span, spans reported after Close are dropped silently
+func (r *otlpReporter) Report(span *jaeger.Span) {
+	select {
+	case <-r.done:
+		return
+	default:
+	}
+	s := otlpSpan{
+		context: span.SpanContext(),
+		name:    span.OperationName(),
+		start:   span.StartTime(),
+		end:     span.StartTime().Add(span.Duration()),
+		tags:    span.Tags(),
+		logs:    span.Logs(),
+	}
+	for _, ref := range span.References() {
+		if ref.Type != opentracing.FollowsFromRef {
+			continue
+		}
+		if sc, ok := ref.ReferencedContext.(jaeger.SpanContext); ok {
+			s.links = append(s.links, sc)
+		}
+	}
+	select {
+	case r.queue <- s:
+	default:
+		atomic.AddUint64(&r.dropped, 1)
+	}
+}
+
+// Close exports queued spans
+func (r *otlpReporter) Close() {
+	r.once.Do(func() {
+		close(r.done)
+		r.wg.Wait()
+		if err := r.sender.close(); err != nil {
+			logger.Error(logger.App, "tracing: can't close OTLP sender: %v", err)
+		}
+	})
+}
+
+func (r *otlpReporter) run() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.opts.batchInterval)
+	defer ticker.Stop()
+	batch := make([]otlpSpan, 0, r.opts.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.export(batch); err != nil {
+			logger.Error(logger.App, "%v", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case s := <-r.queue:
+			batch = append(batch, s)
+			if len(batch) >= r.opts.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+			r.reportDropped()
+		case <-r.done:
+			for {
+				select {
+				case s := <-r.queue:
+					batch = append(batch, s)
+					if len(batch) >= r.opts.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					r.reportDropped()
+					return
+				}
+			}
+		}
+	}
+}
+
+// reportDropped logs number of dropped spans at most once per batch interval
+func (r *otlpReporter) reportDropped() {
+	if dropped := atomic.SwapUint64(&r.dropped, 0); dropped > 0 {
+		logger.Error(logger.App, "tracing: OTLP queue is full, %d spans are dropped", dropped)
+	}
+}
+
+func (r *otlpReporter) export(batch []otlpSpan) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.opts.timeout)
+	defer cancel()
+	if err := r.sender.send(ctx, newOTLPRequest(r.serviceName, batch)); err != nil {
+		return errors.Internal.ErrWrap(ctx, "tracing: OTLP export failed", err).WithLogKV("spans", len(batch))
+	}
+	return nil
+}
+
+// newOTLPRequest converts spans to export request, all spans have the same resource and scope
+func newOTLPRequest(serviceName string, spans []otlpSpan) *coltracepb.ExportTraceServiceRequest {
+	scopeSpans := &tracepb.InstrumentationLibrarySpans{
+		InstrumentationLibrary: &commonpb.InstrumentationLibrary{Name: otlpScopeName},
+		Spans:                  make([]*tracepb.Span, 0, len(spans)),
+	}
+	for _, s := range spans {
+		scopeSpans.Spans = append(scopeSpans.Spans, newOTLPSpan(s))
+	}
+	return &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			Resource: &resourcepb.Resource{
+				Attributes: []*commonpb.KeyValue{
+					otlpKeyValue("service.name", serviceName),
+					otlpKeyValue("telemetry.sdk.language", "go"),
+				},
+			},
+			InstrumentationLibrarySpans: []*tracepb.InstrumentationLibrarySpans{scopeSpans},
+		}},
+	}
+}
+
+func newOTLPSpan(s otlpSpan) *tracepb.Span {
+	span := &tracepb.Span{
+		TraceId:           otlpTraceID(s.context.TraceID()),
+		SpanId:            otlpSpanID(s.context.SpanID()),
+		Name:              s.name,
+		Kind:              otlpKind(s.tags),
+		StartTimeUnixNano: uint64(s.start.UnixNano()),
+		EndTimeUnixNano:   uint64(s.end.UnixNano()),
+	}
+	if parentID := s.context.ParentID(); parentID != 0 {
+		span.ParentSpanId = otlpSpanID(parentID)
+	}
+	for key, value := range s.tags {
+		if key == string(ext.SpanKind) {
+			continue
+		}
+		span.Attributes = append(span.Attributes, otlpKeyValue(key, value))
+	}
+	for _, record := range s.logs {
+		event := &tracepb.Span_Event{TimeUnixNano: uint64(record.Timestamp.UnixNano()), Name: "log"}
+		for _, field := range record.Fields {
+			if field.Key() == "event" {
+				event.Name = fmt.Sprint(field.Value())
+				continue
+			}
+			event.Attributes = append(event.Attributes, otlpKeyValue(field.Key(), field.Value()))
+		}
+		span.Events = append(span.Events, event)
+	}
+	for _, link := range s.links {
+		span.Links = append(span.Links, &tracepb.Span_Link{
+			TraceId: otlpTraceID(link.TraceID()),
+			SpanId:  otlpSpanID(link.SpanID()),
+		})
+	}
+	if failed, ok := s.tags[string(ext.Error)].(bool); ok && failed {
+		// deprecated code is read by collectors older than status code
+		span.Status = &tracepb.Status{
+			Code:           tracepb.Status_STATUS_CODE_ERROR,
+			DeprecatedCode: tracepb.Status_DEPRECATED_STATUS_CODE_UNKNOWN_ERROR,
+		}
+	}
+	return span
+}
+
+func otlpKind(tags opentracing.Tags) tracepb.Span_SpanKind {
+	switch fmt.Sprint(tags[string(ext.SpanKind)]) {
+	case string(ext.SpanKindRPCServerEnum):
+		return tracepb.Span_SPAN_KIND_SERVER
+	case string(ext.SpanKindRPCClientEnum):
+		return tracepb.Span_SPAN_KIND_CLIENT
+	case string(ext.SpanKindProducerEnum):
+		return tracepb.Span_SPAN_KIND_PRODUCER
+	case string(ext.SpanKindConsumerEnum):
+		return tracepb.Span_SPAN_KIND_CONSUMER
+	}
+	return tracepb.Span_SPAN_KIND_INTERNAL
+}
+
+func otlpTraceID(id jaeger.TraceID) []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b[:8], id.High)
+	binary.BigEndian.PutUint64(b[8:], id.Low)
+	return b
+}
+
+func otlpSpanID(id jaeger.SpanID) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	return b
+}
+
+func otlpKeyValue(key string, value interface{}) *commonpb.KeyValue {
+	anyValue := &commonpb.AnyValue{}
+	switch v := value.(type) {
+	case string:
+		anyValue.Value = &commonpb.AnyValue_StringValue{StringValue: v}
+	case bool:
+		anyValue.Value = &commonpb.AnyValue_BoolValue{BoolValue: v}
+	case int:
+		anyValue.Value = &commonpb.AnyValue_IntValue{IntValue: int64(v)}
+	case int32:
+		anyValue.Value = &commonpb.AnyValue_IntValue{IntValue: int64(v)}
+	case int64:
+		anyValue.Value = &commonpb.AnyValue_IntValue{IntValue: v}
+	case uint16:
+		anyValue.Value = &commonpb.AnyValue_IntValue{IntValue: int64(v)}
+	case uint32:
+		anyValue.Value = &commonpb.AnyValue_IntValue{IntValue: int64(v)}
+	case uint64:
+		anyValue.Value = &commonpb.AnyValue_IntValue{IntValue: int64(v)}
+	case float32:
+		anyValue.Value = &commonpb.AnyValue_DoubleValue{DoubleValue: float64(v)}
+	case float64:
+		anyValue.Value = &commonpb.AnyValue_DoubleValue{DoubleValue: v}
+	default:
+		anyValue.Value = &commonpb.AnyValue_StringValue{StringValue: fmt.Sprint(v)}
+	}
+	return &commonpb.KeyValue{Key: key, Value: anyValue}
+}
+
+type httpSender struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newHTTPSender(url string, opts *otlpOptions) *httpSender {
+	return &httpSender{url: url, headers: opts.headers, client: &http.Client{}}
+}
+
+func (s *httpSender) send(ctx context.Context, request *coltracepb.ExportTraceServiceRequest) error {
+	body, err := proto.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, data)
+	}
+	return nil
+}
+
+func (s *httpSender) close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+type grpcSender struct {
+	conn    *grpc.ClientConn
+	client  coltracepb.TraceServiceClient
+	headers metadata.MD
+}
+
+func newGRPCSender(conn *grpc.ClientConn, opts *otlpOptions) *grpcSender {
+	return &grpcSender{conn: conn, client: coltracepb.NewTraceServiceClient(conn), headers: metadata.New(opts.headers)}
+}
+
+func (s *grpcSender) send(ctx context.Context, request *coltracepb.ExportTraceServiceRequest) error {
+	if len(s.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, s.headers)
+	}
+	_, err := s.client.Export(ctx, request)
+	return err
+}
+
+func (s *grpcSender) close() error {
+	return s.conn.Close()
+}
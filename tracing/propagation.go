@@ -0,0 +1,122 @@
+package tracing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+)
+
+// W3CHeader is header of W3C tracecontext
+const W3CHeader = "traceparent"
+
+// Propagation is format of trace context in headers and grpc metadata
+type Propagation struct {
+	injector  jaeger.Injector
+	extractor jaeger.Extractor
+}
+
+var (
+	// PropagationW3C is W3C tracecontext "traceparent" header, https://www.w3.org/TR/trace-context/
+	PropagationW3C = Propagation{injector: w3cPropagation{}, extractor: w3cPropagation{}}
+	// PropagationJaeger is jaeger "uber-trace-id" header with "uberctx-" baggage
+	PropagationJaeger = newJaegerPropagation()
+)
+
+func newJaegerPropagation() Propagation {
+	p := jaeger.NewHTTPHeaderPropagator((&jaeger.HeadersConfig{}).ApplyDefaults(), *jaeger.NewNullMetrics())
+	return Propagation{injector: p, extractor: p}
+}
+
+// propagator injects all formats and extracts the first found
+type propagator []Propagation
+
+func newPropagator(list []Propagation) propagator {
+	return propagator(list)
+}
+
+func (p propagator) Inject(sc jaeger.SpanContext, carrier interface{}) error {
+	for _, item := range p {
+		if item.injector == nil {
+			continue
+		}
+		if err := item.injector.Inject(sc, carrier); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p propagator) Extract(carrier interface{}) (jaeger.SpanContext, error) {
+	for _, item := range p {
+		if item.extractor == nil {
+			continue
+		}
+		sc, err := item.extractor.Extract(carrier)
+		if err == nil {
+			return sc, nil
+		}
+		if err != opentracing.ErrSpanContextNotFound {
+			return jaeger.SpanContext{}, err
+		}
+	}
+	return jaeger.SpanContext{}, opentracing.ErrSpanContextNotFound
+}
+
+type w3cPropagation struct{}
+
+// Inject writes "00-<trace id>-<span id>-<flags>", only sampled flag is defined by version 00
+func (w3cPropagation) Inject(sc jaeger.SpanContext, carrier interface{}) error {
+	writer, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+	flags := 0
+	if sc.IsSampled() {
+		flags = 1
+	}
+	traceID := sc.TraceID()
+	writer.Set(W3CHeader, fmt.Sprintf("00-%016x%016x-%016x-%02x", traceID.High, traceID.Low, uint64(sc.SpanID()), flags))
+	return nil
+}
+
+func (w3cPropagation) Extract(carrier interface{}) (jaeger.SpanContext, error) {
+	reader, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return jaeger.SpanContext{}, opentracing.ErrInvalidCarrier
+	}
+	var header string
+	err := reader.ForeachKey(func(key, val string) error {
+		if strings.ToLower(key) == W3CHeader {
+			header = val
+		}
+		return nil
+	})
+	if err != nil {
+		return jaeger.SpanContext{}, err
+	}
+	if header == "" {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextNotFound
+	}
+	return parseTraceparent(strings.TrimSpace(header))
+}
+
+// parseTraceparent accepts future versions too, they must start with version 00 fields
+func parseTraceparent(header string) (jaeger.SpanContext, error) {
+	parts := strings.Split(header, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 ||
+		parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+	high, errHigh := strconv.ParseUint(parts[1][:16], 16, 64)
+	low, errLow := strconv.ParseUint(parts[1][16:], 16, 64)
+	spanID, errSpan := strconv.ParseUint(parts[2], 16, 64)
+	flags, errFlags := strconv.ParseUint(parts[3], 16, 8)
+	if errHigh != nil || errLow != nil || errSpan != nil || errFlags != nil || (high == 0 && low == 0) || spanID == 0 {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+	traceID := jaeger.TraceID{High: high, Low: low}
+	return jaeger.NewSpanContext(traceID, jaeger.SpanID(spanID), 0, flags&1 == 1, nil), nil
+}
@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"context"
+
+	errors "github.com/sanches1984/gopkg-errors"
+	"github.com/uber/jaeger-client-go"
+)
+
+// Samplers decide only for new traces. Spans continuing trace from incoming request follow
+// sampled flag of remote parent, so sampling is parent-based and traces are not broken between services.
+
+// Sampler decides whether new trace is sampled, zero value samples all traces
+type Sampler struct {
+	sampler jaeger.Sampler
+}
+
+// SamplerAlways samples all new traces
+func SamplerAlways() Sampler {
+	return Sampler{sampler: jaeger.NewConstSampler(true)}
+}
+
+// SamplerNever samples no new traces, traces sampled by callers are still recorded
+func SamplerNever() Sampler {
+	return Sampler{sampler: jaeger.NewConstSampler(false)}
+}
+
+// SamplerRatio samples ratio (0..1) of new traces
+func SamplerRatio(ratio float64) (Sampler, error) {
+	sampler, err := jaeger.NewProbabilisticSampler(ratio)
+	if err != nil {
+		return Sampler{}, errors.Internal.ErrWrap(context.Background(), "tracing: invalid sampler ratio", err)
+	}
+	return Sampler{sampler: sampler}, nil
+}
+
+// SamplerRateLimited samples at most perSecond new traces per second
+func SamplerRateLimited(perSecond float64) Sampler {
+	return Sampler{sampler: jaeger.NewRateLimitingSampler(perSecond)}
+}
+
+func (s Sampler) jaegerSampler() jaeger.Sampler {
+	if s.sampler == nil {
+		return jaeger.NewConstSampler(true)
+	}
+	return s.sampler
+}
@@ -2,22 +2,67 @@ package tracing
 
 import (
 	"context"
+	"io"
+
 	"github.com/opentracing/opentracing-go"
 	"github.com/uber/jaeger-client-go"
-	"io"
 )
 
 var appTracer *opentracing.Tracer
 
+type options struct {
+	sampler     Sampler
+	propagation []Propagation
+}
+
+type Option func(o *options)
+
+// WithSampler sets sampler of new traces, by default all traces are sampled
+func WithSampler(sampler Sampler) Option {
+	return func(o *options) {
+		o.sampler = sampler
+	}
+}
+
+// WithPropagation sets formats of trace context in headers and grpc metadata. All formats are injected,
+// the first found one is extracted. By default W3C tracecontext and jaeger formats are used.
+func WithPropagation(propagation ...Propagation) Option {
+	return func(o *options) {
+		o.propagation = propagation
+	}
+}
+
+// InitTracer sends all traces to jaeger agent by UDP
 func InitTracer(serviceName, addr string) (io.Closer, error) {
-	transport, err := jaeger.NewUDPTransport(addr, 0)
+	return Init(serviceName, JaegerUDP(addr))
+}
+
+// Init creates global opentracing tracer which sends spans with exporter,
+// so opentracing instrumentation (grpc interceptors, StartSpan) works with any backend
+func Init(serviceName string, exporter Exporter, opts ...Option) (io.Closer, error) {
+	o := &options{
+		sampler:     SamplerAlways(),
+		propagation: []Propagation{PropagationW3C, PropagationJaeger},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	reporter, err := exporter.reporter(serviceName)
 	if err != nil {
 		return nil, err
 	}
+	propagator := newPropagator(o.propagation)
 	tracer, tracerCloser := jaeger.NewTracer(
 		serviceName,
-		jaeger.NewConstSampler(true),
-		jaeger.NewRemoteReporter(transport),
+		o.sampler.jaegerSampler(),
+		reporter,
+		// W3C trace id has 128 bits
+		jaeger.TracerOptions.Gen128Bit(true),
+		jaeger.TracerOptions.Injector(opentracing.HTTPHeaders, propagator),
+		jaeger.TracerOptions.Extractor(opentracing.HTTPHeaders, propagator),
+		jaeger.TracerOptions.Injector(opentracing.TextMap, propagator),
+		jaeger.TracerOptions.Extractor(opentracing.TextMap, propagator),
 	)
 	appTracer = &tracer
 	opentracing.InitGlobalTracer(tracer)
@@ -38,3 +83,15 @@ func StartSpan(ctx context.Context, operationName string) opentracing.Span {
 
 	return span
 }
+
+// TraceID returns trace id of span from context, empty without span or with non jaeger tracer
+func TraceID(ctx context.Context) string {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return ""
+	}
+	if sc, ok := span.Context().(jaeger.SpanContext); ok && sc.TraceID().IsValid() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
@@ -0,0 +1,318 @@
+package tracing
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-client-go"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPropagation(t *testing.T) {
+	tracer, closer := jaeger.NewTracer("test", SamplerAlways().jaegerSampler(), jaeger.NewNullReporter(),
+		jaeger.TracerOptions.Gen128Bit(true),
+		jaeger.TracerOptions.Injector(opentracing.HTTPHeaders, newPropagator([]Propagation{PropagationW3C, PropagationJaeger})),
+		jaeger.TracerOptions.Extractor(opentracing.HTTPHeaders, newPropagator([]Propagation{PropagationW3C, PropagationJaeger})),
+	)
+	defer closer.Close()
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		span := tracer.StartSpan("client")
+		defer span.Finish()
+		header := http.Header{}
+		assert.Nil(t, tracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header)))
+		assert.NotEmpty(t, header.Get(W3CHeader))
+		assert.NotEmpty(t, header.Get(jaeger.TraceContextHeaderName))
+
+		header.Del(jaeger.TraceContextHeaderName)
+		sc, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header))
+		assert.Nil(t, err)
+		assert.Equal(t, span.Context().(jaeger.SpanContext).TraceID(), sc.(jaeger.SpanContext).TraceID())
+		assert.Equal(t, span.Context().(jaeger.SpanContext).SpanID(), sc.(jaeger.SpanContext).SpanID())
+	})
+
+	t.Run("Parse", func(t *testing.T) {
+		sc, err := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		assert.Nil(t, err)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+		assert.True(t, sc.IsSampled())
+
+		for _, header := range []string{
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			"garbage",
+		} {
+			_, err := parseTraceparent(header)
+			assert.Equal(t, opentracing.ErrSpanContextCorrupted, err, header)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(http.Header{}))
+		assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+	})
+}
+
+func TestParentBasedSampling(t *testing.T) {
+	extract := func(sampler Sampler, flags string) bool {
+		tracer, closer := jaeger.NewTracer("test", sampler.jaegerSampler(), jaeger.NewNullReporter(),
+			jaeger.TracerOptions.Extractor(opentracing.HTTPHeaders, newPropagator([]Propagation{PropagationW3C})))
+		defer closer.Close()
+		header := http.Header{}
+		header.Set(W3CHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-"+flags)
+		parent, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header))
+		assert.Nil(t, err)
+		span := tracer.StartSpan("server", opentracing.ChildOf(parent))
+		defer span.Finish()
+		return span.Context().(jaeger.SpanContext).IsSampled()
+	}
+	assert.True(t, extract(SamplerNever(), "01"))
+	assert.False(t, extract(SamplerAlways(), "00"))
+
+	_, err := SamplerRatio(2)
+	assert.NotNil(t, err)
+	ratio, err := SamplerRatio(0.5)
+	assert.Nil(t, err)
+	assert.NotNil(t, ratio.sampler)
+	assert.NotNil(t, SamplerRateLimited(10).sampler)
+
+	tracer, closer := jaeger.NewTracer("test", Sampler{}.jaegerSampler(), jaeger.NewNullReporter())
+	defer closer.Close()
+	assert.True(t, tracer.StartSpan("root").Context().(jaeger.SpanContext).IsSampled())
+}
+
+func TestOTLP(t *testing.T) {
+	finishSpan := func() (jaeger.SpanContext, jaeger.SpanContext) {
+		parent := GetTracer().StartSpan("parent")
+		parent.Finish()
+		span := GetTracer().StartSpan("export-test", opentracing.ChildOf(parent.Context()), ext.SpanKindRPCServer)
+		span.SetTag("error", true)
+		span.SetTag("attempts", 2)
+		span.LogKV("event", "retry", "attempt", 2)
+		span.Finish()
+		return parent.Context().(jaeger.SpanContext), span.Context().(jaeger.SpanContext)
+	}
+	attributes := func(list []*commonpb.KeyValue) map[string]interface{} {
+		values := make(map[string]interface{}, len(list))
+		for _, kv := range list {
+			switch v := kv.GetValue().GetValue().(type) {
+			case *commonpb.AnyValue_StringValue:
+				values[kv.GetKey()] = v.StringValue
+			case *commonpb.AnyValue_BoolValue:
+				values[kv.GetKey()] = v.BoolValue
+			case *commonpb.AnyValue_IntValue:
+				values[kv.GetKey()] = v.IntValue
+			}
+		}
+		return values
+	}
+	assertRequests := func(requests []*coltracepb.ExportTraceServiceRequest, parent, sc jaeger.SpanContext) {
+		var spans []*tracepb.Span
+		for _, req := range requests {
+			for _, resourceSpans := range req.GetResourceSpans() {
+				assert.Equal(t, map[string]interface{}{"service.name": "test", "telemetry.sdk.language": "go"},
+					attributes(resourceSpans.GetResource().GetAttributes()))
+				for _, scopeSpans := range resourceSpans.GetInstrumentationLibrarySpans() {
+					assert.Equal(t, otlpScopeName, scopeSpans.GetInstrumentationLibrary().GetName())
+					spans = append(spans, scopeSpans.GetSpans()...)
+				}
+			}
+		}
+		assert.Len(t, spans, 2)
+		for _, span := range spans {
+			if span.GetName() != "export-test" {
+				assert.Equal(t, "parent", span.GetName())
+				assert.Equal(t, tracepb.Span_SPAN_KIND_INTERNAL, span.GetKind())
+				assert.Nil(t, span.GetStatus())
+				continue
+			}
+			assert.Equal(t, otlpTraceID(sc.TraceID()), span.GetTraceId())
+			assert.Equal(t, otlpSpanID(sc.SpanID()), span.GetSpanId())
+			assert.Equal(t, otlpSpanID(parent.SpanID()), span.GetParentSpanId())
+			assert.Equal(t, tracepb.Span_SPAN_KIND_SERVER, span.GetKind())
+			assert.True(t, span.GetStartTimeUnixNano() > 0)
+			assert.True(t, span.GetEndTimeUnixNano() >= span.GetStartTimeUnixNano())
+			assert.Equal(t, map[string]interface{}{"error": true, "attempts": int64(2)}, attributes(span.GetAttributes()))
+			assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, span.GetStatus().GetCode())
+			if assert.Len(t, span.GetEvents(), 1) {
+				assert.Equal(t, "retry", span.GetEvents()[0].GetName())
+				assert.Equal(t, map[string]interface{}{"attempt": int64(2)}, attributes(span.GetEvents()[0].GetAttributes()))
+			}
+		}
+	}
+
+	t.Run("HTTP", func(t *testing.T) {
+		var mu sync.Mutex
+		var requests []*coltracepb.ExportTraceServiceRequest
+		var auth, contentType string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, _ := ioutil.ReadAll(r.Body)
+			req := &coltracepb.ExportTraceServiceRequest{}
+			if err := proto.Unmarshal(data, req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			mu.Lock()
+			requests = append(requests, req)
+			auth = r.Header.Get("Authorization")
+			contentType = r.Header.Get("Content-Type")
+			mu.Unlock()
+		}))
+		defer server.Close()
+
+		closer, err := Init("test", OTLPHTTP(server.URL+"/v1/traces", WithOTLPHeaders(map[string]string{"Authorization": "Bearer token"})))
+		assert.Nil(t, err)
+		parent, sc := finishSpan()
+		assert.Nil(t, closer.Close())
+
+		mu.Lock()
+		defer mu.Unlock()
+		assertRequests(requests, parent, sc)
+		assert.Equal(t, "Bearer token", auth)
+		assert.Equal(t, "application/x-protobuf", contentType)
+	})
+
+	t.Run("GRPC", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		collector := &testCollector{}
+		server := grpc.NewServer()
+		coltracepb.RegisterTraceServiceServer(server, collector)
+		go func() { _ = server.Serve(listener) }()
+		defer server.Stop()
+
+		closer, err := Init("test", OTLPGRPC(listener.Addr().String(), WithOTLPBatch(1, time.Second)))
+		assert.Nil(t, err)
+		parent, sc := finishSpan()
+		assert.Eventually(t, func() bool {
+			return len(collector.get()) == 2
+		}, time.Second, 10*time.Millisecond)
+		assert.Nil(t, closer.Close())
+
+		assertRequests(collector.get(), parent, sc)
+	})
+
+	t.Run("Dropped", func(t *testing.T) {
+		sender := &testSender{started: make(chan struct{}, 1), release: make(chan struct{})}
+		opts, err := newOTLPOptions([]OTLPOption{WithOTLPBatch(1, time.Hour), WithOTLPQueueSize(1)})
+		assert.Nil(t, err)
+		reporter := newOTLPReporter("test", opts, sender)
+		tracer, tracerCloser := jaeger.NewTracer("test", SamplerAlways().jaegerSampler(), reporter)
+
+		tracer.StartSpan("exported").Finish()
+		<-sender.started
+		tracer.StartSpan("queued").Finish()
+		tracer.StartSpan("dropped").Finish()
+		tracer.StartSpan("dropped").Finish()
+		assert.Equal(t, uint64(2), atomic.LoadUint64(&reporter.dropped))
+
+		close(sender.release)
+		assert.Nil(t, tracerCloser.Close())
+		assert.Equal(t, uint64(0), atomic.LoadUint64(&reporter.dropped))
+		assert.Equal(t, []string{"exported", "queued"}, sender.get())
+
+		assert.NotPanics(t, func() {
+			tracer.StartSpan("closed").Finish()
+		})
+		assert.Equal(t, uint64(0), atomic.LoadUint64(&reporter.dropped))
+		assert.Len(t, reporter.queue, 0)
+	})
+}
+
+func TestOTLPOptions(t *testing.T) {
+	for name, opt := range map[string]OTLPOption{
+		"ZeroBatchSize":     WithOTLPBatch(0, time.Second),
+		"ZeroBatchInterval": WithOTLPBatch(1, 0),
+		"NegativeQueueSize": WithOTLPQueueSize(-1),
+		"ZeroTimeout":       WithOTLPTimeout(0),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := OTLPHTTP("http://127.0.0.1:4318/v1/traces", opt).reporter("test")
+			assert.NotNil(t, err)
+			_, err = OTLPGRPC("127.0.0.1:4317", opt).reporter("test")
+			assert.NotNil(t, err)
+			_, err = Init("test", OTLPHTTP("http://127.0.0.1:4318/v1/traces", opt))
+			assert.NotNil(t, err)
+		})
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		o, err := newOTLPOptions(nil)
+		assert.Nil(t, err)
+		assert.Equal(t, DefaultOTLPBatchSize, o.batchSize)
+		assert.Equal(t, DefaultOTLPBatchInterval, o.batchInterval)
+		assert.Equal(t, DefaultOTLPQueueSize, o.queueSize)
+		assert.Equal(t, DefaultOTLPTimeout, o.timeout)
+	})
+}
+
+// testSender blocks export until release is closed
+type testSender struct {
+	started chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	names   []string
+}
+
+func (s *testSender) send(ctx context.Context, request *coltracepb.ExportTraceServiceRequest) error {
+	select {
+	case s.started <- struct{}{}:
+	default:
+	}
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, resourceSpans := range request.GetResourceSpans() {
+		for _, scopeSpans := range resourceSpans.GetInstrumentationLibrarySpans() {
+			for _, span := range scopeSpans.GetSpans() {
+				s.names = append(s.names, span.GetName())
+			}
+		}
+	}
+	return nil
+}
+
+func (s *testSender) close() error {
+	return nil
+}
+
+func (s *testSender) get() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.names...)
+}
+
+type testCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+	mu       sync.Mutex
+	requests []*coltracepb.ExportTraceServiceRequest
+}
+
+func (c *testCollector) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func (c *testCollector) get() []*coltracepb.ExportTraceServiceRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*coltracepb.ExportTraceServiceRequest(nil), c.requests...)
+}
@@ -18,3 +18,27 @@ func NewValidateServerInterceptor(validate *validator.Validate) grpc.UnaryServer
 		return handler(ctx, req)
 	}
 }
+
+// NewValidateStreamServerInterceptor validates first message received from client stream
+func NewValidateStreamServerInterceptor(validate *validator.Validate) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &validateServerStream{ServerStream: ss, validate: validate})
+	}
+}
+
+type validateServerStream struct {
+	grpc.ServerStream
+	validate *validator.Validate
+	received bool
+}
+
+func (s *validateServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.received {
+		return nil
+	}
+	s.received = true
+	return s.validate.Struct(m)
+}